@@ -0,0 +1,9 @@
+package api
+
+import "github.com/bigredeye/notmanytask/internal/models"
+
+type AuditResponse struct {
+	Status
+
+	Records []models.AuditRecord
+}
@@ -0,0 +1,9 @@
+package api
+
+import "github.com/bigredeye/notmanytask/internal/deadlines"
+
+type DeadlinesStatusResponse struct {
+	Status
+
+	Groups []deadlines.GroupStatus `json:"Groups,omitempty"`
+}
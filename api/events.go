@@ -0,0 +1,30 @@
+package api
+
+// ScoreEvent is pushed to the standings and home pages when the user scores change.
+// Only the changed task groups and tasks are listed.
+type ScoreEvent struct {
+	Login    string `json:"login"`
+	Score    int    `json:"score"`
+	MaxScore int    `json:"max_score"`
+	// Mark is set only if the group shows marks
+	Mark *float64 `json:"mark,omitempty"`
+
+	Groups []GroupScoreEvent `json:"groups,omitempty"`
+	Tasks  []TaskScoreEvent  `json:"tasks,omitempty"`
+}
+
+type GroupScoreEvent struct {
+	Title    string `json:"title"`
+	Score    int    `json:"score"`
+	MaxScore int    `json:"max_score"`
+}
+
+type TaskScoreEvent struct {
+	Task       string `json:"task"`
+	Score      int    `json:"score"`
+	MaxScore   int    `json:"max_score"`
+	Status     string `json:"status"`
+	Overridden bool   `json:"overridden,omitempty"`
+	Retaken    bool   `json:"retaken,omitempty"`
+	Dropped    bool   `json:"dropped,omitempty"`
+}
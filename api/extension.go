@@ -0,0 +1,20 @@
+package api
+
+import "github.com/bigredeye/notmanytask/internal/models"
+
+type ExtensionRequest struct {
+	Task     string `json:"task" form:"task"`
+	Login    string `json:"login" form:"login"`
+	Deadline string `json:"deadline" form:"deadline"`
+	Reason   string `json:"reason" form:"reason"`
+}
+
+type ExtensionResponse struct {
+	Status
+}
+
+type ListExtensionsResponse struct {
+	Status
+
+	Extensions []models.Extension
+}
@@ -1,8 +1,7 @@
 package api
 
 type FlagRequest struct {
-	Token string `json:"token" form:"token"`
-	Task  string `json:"task" form:"task"`
+	Task string `json:"task" form:"task"`
 }
 
 type FlagResponse struct {
@@ -1,7 +1,6 @@
 package api
 
 type OverrideRequest struct {
-	Token  string `json:"token" form:"token"`
 	Task   string `json:"task" form:"task"`
 	Login  string `json:"login" form:"login"`
 	Score  int    `json:"score" form:"score"`
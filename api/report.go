@@ -2,13 +2,19 @@ package api
 
 // IDs are strings for compatibility
 type ReportRequest struct {
-	Token       string `json:"token" form:"token"`
 	Task        string `json:"task" form:"task"`
 	UserID      string `json:"user_id" form:"user_id"`
 	PipelineID  string `json:"pipeline_id" form:"pipeline_id"`
 	ProjectName string `json:"project_name" form:"project_name"`
 	Failed      int    `json:"failed,omitempty" form:"failed"`
 	Status      string `json:"status,omitempty" form:"status"`
+
+	// Partial credit: either fraction in [0, 1] or points out of max points
+	Fraction  *float64 `json:"fraction,omitempty" form:"fraction"`
+	Points    *float64 `json:"points,omitempty" form:"points"`
+	MaxPoints float64  `json:"max_points,omitempty" form:"max_points"`
+	Passed    int      `json:"passed,omitempty" form:"passed"`
+	Total     int      `json:"total,omitempty" form:"total"`
 }
 
 type ReportResponse struct {
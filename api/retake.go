@@ -0,0 +1,10 @@
+package api
+
+type RetakeRequest struct {
+	Login  string `json:"login" form:"login"`
+	Policy string `json:"policy" form:"policy"`
+}
+
+type RetakeResponse struct {
+	Status
+}
@@ -0,0 +1,10 @@
+package api
+
+type RoleRequest struct {
+	Login string `json:"login" form:"login"`
+	Role  string `json:"role" form:"role"`
+}
+
+type RoleResponse struct {
+	Status
+}
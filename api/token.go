@@ -0,0 +1,24 @@
+package api
+
+import "github.com/bigredeye/notmanytask/internal/models"
+
+type TokenRequest struct {
+	Name   string   `json:"name" form:"name"`
+	Scopes []string `json:"scopes" form:"scopes"`
+	// Group restricts the token to the students of the group, empty means any group
+	Group string `json:"group,omitempty" form:"group"`
+	// ExpiresAt is in the deadlines date format, empty means the token never expires
+	ExpiresAt string `json:"expires_at,omitempty" form:"expires_at"`
+}
+
+type TokenResponse struct {
+	Status
+	// Token is set only on creation, it cannot be recovered later
+	Token string `json:"token,omitempty"`
+}
+
+type ListTokensResponse struct {
+	Status
+
+	Tokens []models.APIToken
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+)
+
+func makeAuditCommand() *cobra.Command {
+	var user string
+	var actor string
+	var action string
+	var since string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Show who changed scores, groups and deadlines, the latest changes first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dumpAudit(user, actor, action, since, limit)
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "Affected user name")
+	cmd.Flags().StringVar(&actor, "actor", "", "Actor, e.g. token:grader or user:ivanov")
+	cmd.Flags().StringVar(&action, "action", "", "Action, e.g. override or changeGroup")
+	cmd.Flags().StringVar(&since, "since", "", "Show changes after the moment, e.g. \"31-12-2022 23:59\"")
+	cmd.Flags().IntVar(&limit, "limit", 100, "Maximum number of records, zero for all")
+
+	return cmd
+}
+
+func dumpAudit(user, actor, action, since string, limit int) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	records, err := nmt.LoadAuditRecords(user, actor, action, since, limit)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s -> %s\n", record.CreatedAt.Format("02-01-2006 15:04:05"), record.Actor, record.Action, record.Target, record.OldValue, record.NewValue)
+	}
+
+	return nil
+}
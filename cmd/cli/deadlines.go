@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/spf13/cobra"
+)
+
+func makeLintDeadlinesCommand() *cobra.Command {
+	options := deadlines.LintOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "lint <file>",
+		Short: "Check deadlines file",
+		Args:  cobra.ExactArgs(1),
+		// Lint failures are not usage errors
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return lintDeadlines(args[0], options)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Format, "format", "v2", "Deadlines format, v1 or v2")
+	cmd.Flags().Float64Var(&options.ExpectedWeight, "weight", 0, "Expected sum of scoring group weights, 0 to skip the check")
+
+	return cmd
+}
+
+func lintDeadlines(path string, options deadlines.LintOptions) error {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	diagnostics := deadlines.Lint(body, options)
+	for _, diagnostic := range diagnostics {
+		if diagnostic.Line == 0 {
+			fmt.Printf("%s: %s\n", path, diagnostic.Message)
+		} else {
+			fmt.Printf("%s:%d: %s\n", path, diagnostic.Line, diagnostic.Message)
+		}
+	}
+
+	if len(diagnostics) > 0 {
+		return fmt.Errorf("found %d problem(s) in %s", len(diagnostics), path)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func makeExportStandingsCommand() *cobra.Command {
+	var group string
+	var at string
+	var format string
+	var columns string
+	var names string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export standings with per-task scores to csv, xlsx or ods",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return exportStandings(group, at, format, columns, names, output)
+		},
+	}
+
+	cmd.Flags().StringVar(&group, "group", "hse", "Group name")
+	cmd.Flags().StringVar(&at, "at", "", "Export standings as they were at the moment, e.g. \"31-12-2022 23:59\"")
+	cmd.Flags().StringVar(&format, "format", "csv", "File format: csv, xlsx or ods")
+	cmd.Flags().StringVar(&columns, "columns", "", "Comma separated columns: name, login, tasks, totals, score, mark, grade")
+	cmd.Flags().StringVar(&names, "names", "", "Name format: last-first, first-last or last-initials")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output file, stdout by default")
+
+	return cmd
+}
+
+func exportStandings(group, at, format, columns, names, output string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	data, err := nmt.ExportStandings(group, at, format, columns, names)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if err = os.WriteFile(output, data, 0644); err != nil {
+		return err
+	}
+
+	log.Info("Exported standings",
+		zap.String("group", group),
+		zap.String("format", format),
+		zap.String("output", output),
+	)
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func makeExtendCommand() *cobra.Command {
+	var task string
+	var user string
+	var deadline string
+	var reason string
+	var remove bool
+
+	cmd := &cobra.Command{
+		Use:   "extend",
+		Short: "Extend task or task group deadline for the user",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if remove {
+				return removeExtension(task, user)
+			}
+			return extendDeadline(task, user, deadline, reason)
+		},
+	}
+
+	cmd.Flags().StringVar(&task, "task", "", "Task name or task group title")
+	cmd.Flags().StringVar(&user, "user", "", "User name")
+	cmd.Flags().StringVar(&deadline, "deadline", "", "New deadline, e.g. \"31-12-2022 23:59\"")
+	cmd.Flags().StringVar(&reason, "reason", "", "Extension reason")
+	cmd.Flags().BoolVar(&remove, "remove", false, "Remove existing extension")
+
+	return cmd
+}
+
+func extendDeadline(task, user, deadline, reason string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	err = nmt.ExtendDeadline(user, task, deadline, reason)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Extended deadline",
+		zap.String("task", task),
+		zap.String("user", user),
+		zap.String("deadline", deadline),
+		zap.String("reason", reason),
+	)
+
+	return nil
+}
+
+func removeExtension(task, user string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	err = nmt.RemoveExtension(user, task)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Removed extension",
+		zap.String("task", task),
+		zap.String("user", user),
+	)
+
+	return nil
+}
+
+func makeDumpExtensionsCommand() *cobra.Command {
+	var user string
+	cmd := &cobra.Command{
+		Use:   "extensions",
+		Short: "Dump deadline extensions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return dumpExtensions(user)
+		},
+	}
+	cmd.Flags().StringVar(&user, "user", "", "User name, all users if empty")
+
+	return cmd
+}
+
+func dumpExtensions(user string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	extensions, err := nmt.LoadExtensions(user)
+	if err != nil {
+		return err
+	}
+
+	for _, extension := range extensions {
+		fmt.Printf("%s\t%s\t%s\t%s\n", extension.GitlabLogin, extension.Task, extension.Deadline.Format("02-01-2006 15:04"), extension.Reason)
+	}
+
+	return nil
+}
@@ -33,6 +33,16 @@ var (
 		Use:   "dump",
 		Short: "Dump various info",
 	}
+
+	deadlinesCmd = &cobra.Command{
+		Use:   "deadlines",
+		Short: "Work with deadlines files",
+	}
+
+	tokenCmd = &cobra.Command{
+		Use:   "token",
+		Short: "Manage API tokens",
+	}
 )
 
 func initLogging() {
@@ -46,8 +56,20 @@ func initLogging() {
 func initCommands() {
     dumpCmd.AddCommand(makeDumpStandingsCommand())
     dumpCmd.AddCommand(makeDumpSuccessfulSubmits())
+	dumpCmd.AddCommand(makeDumpExtensionsCommand())
 	rootCmd.AddCommand(makeOverrideCommand())
+	rootCmd.AddCommand(makeExtendCommand())
+	rootCmd.AddCommand(makeRetakeCommand())
+	rootCmd.AddCommand(makeAuditCommand())
+	rootCmd.AddCommand(makeRoleCommand())
+	rootCmd.AddCommand(makeExportStandingsCommand())
+	deadlinesCmd.AddCommand(makeLintDeadlinesCommand())
+	tokenCmd.AddCommand(makeCreateTokenCommand())
+	tokenCmd.AddCommand(makeListTokensCommand())
+	tokenCmd.AddCommand(makeRevokeTokenCommand())
 	rootCmd.AddCommand(dumpCmd)
+	rootCmd.AddCommand(deadlinesCmd)
+	rootCmd.AddCommand(tokenCmd)
 }
 
 func init() {
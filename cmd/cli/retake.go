@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func makeRetakeCommand() *cobra.Command {
+	var user string
+	var policy string
+
+	cmd := &cobra.Command{
+		Use:   "retake",
+		Short: "Assign retake policy to the user, empty policy removes the retake",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setRetakePolicy(user, policy)
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "User name")
+	cmd.Flags().StringVar(&policy, "policy", "", "Retake policy name from the deadlines")
+
+	return cmd
+}
+
+func setRetakePolicy(user, policy string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	err = nmt.SetRetakePolicy(user, policy)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Set retake policy",
+		zap.String("user", user),
+		zap.String("policy", policy),
+	)
+
+	return nil
+}
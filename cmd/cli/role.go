@@ -0,0 +1,46 @@
+package main
+
+import (
+	"os"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func makeRoleCommand() *cobra.Command {
+	var user string
+	var role string
+
+	cmd := &cobra.Command{
+		Use:   "role",
+		Short: "Assign role to the user: student, ta or admin",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return setRole(user, role)
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "User name")
+	cmd.Flags().StringVar(&role, "role", "student", "Role name")
+
+	return cmd
+}
+
+func setRole(user, role string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	err = nmt.SetRole(user, role)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Set role",
+		zap.String("user", user),
+		zap.String("role", role),
+	)
+
+	return nil
+}
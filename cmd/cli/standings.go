@@ -12,25 +12,27 @@ import (
 
 func makeDumpStandingsCommand() *cobra.Command {
 	var group string
+	var at string
 	cmd := &cobra.Command{
 		Use:   "standings",
 		Short: "Dump standings",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return dumpStandings(group)
+			return dumpStandings(group, at)
 		},
 	}
 	cmd.Flags().StringVar(&group, "group", "hse", "Group name")
+	cmd.Flags().StringVar(&at, "at", "", "Dump standings as they were at the moment, e.g. \"31-12-2022 23:59\"")
 
 	return cmd
 }
 
-func dumpStandings(group string) error {
+func dumpStandings(group, at string) error {
 	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
 	if err != nil {
 		return err
 	}
 
-	standings, err := nmt.LoadStandings(group)
+	standings, err := nmt.LoadStandingsAt(group, at)
 	if err != nil {
 		return err
 	}
@@ -42,7 +44,11 @@ func dumpStandings(group string) error {
 	})
 
 	for _, user := range standings.Users {
-		fmt.Printf("%s\t%.3f\n", username(&user.User), user.FinalMark)
+		if user.FinalGrade != nil {
+			fmt.Printf("%s\t%.3f\t%g\n", username(&user.User), user.FinalMark, *user.FinalGrade)
+		} else {
+			fmt.Printf("%s\t%.3f\n", username(&user.User), user.FinalMark)
+		}
 	}
 
 	return nil
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bigredeye/notmanytask/pkg/client/notmanytask"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func makeCreateTokenCommand() *cobra.Command {
+	var name string
+	var scopes []string
+	var group string
+	var expires string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create API token, it is printed only once",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return createToken(name, scopes, group, expires)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Unique token name, e.g. grader")
+	cmd.Flags().StringSliceVar(&scopes, "scope", nil, "Granted scopes: report, flag, override, read-standings, manage-users")
+	cmd.Flags().StringVar(&group, "group", "", "Restrict token to the group students")
+	cmd.Flags().StringVar(&expires, "expires", "", "Expiration moment, e.g. \"31-12-2022 23:59\"")
+
+	return cmd
+}
+
+func makeListTokensCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List API tokens",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return listTokens()
+		},
+	}
+}
+
+func makeRevokeTokenCommand() *cobra.Command {
+	var name string
+
+	cmd := &cobra.Command{
+		Use:   "revoke",
+		Short: "Revoke API token",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return revokeToken(name)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "", "Token name")
+
+	return cmd
+}
+
+func createToken(name string, scopes []string, group, expires string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	token, err := nmt.CreateToken(name, scopes, group, expires)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Created token",
+		zap.String("name", name),
+		zap.Strings("scopes", scopes),
+		zap.String("group", group),
+	)
+	fmt.Println(token)
+
+	return nil
+}
+
+func listTokens() error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	tokens, err := nmt.LoadTokens()
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		state := "active"
+		if token.RevokedAt != nil {
+			state = "revoked " + token.RevokedAt.Format("02-01-2006 15:04")
+		} else if token.ExpiresAt != nil {
+			state = "expires " + token.ExpiresAt.Format("02-01-2006 15:04")
+		}
+		lastUsed := "never"
+		if token.LastUsedAt != nil {
+			lastUsed = token.LastUsedAt.Format("02-01-2006 15:04")
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\tused %s\n", token.Name, strings.ReplaceAll(token.Scopes, ",", " "), token.GroupName, state, lastUsed)
+	}
+
+	return nil
+}
+
+func revokeToken(name string) error {
+	nmt, err := notmanytask.NewClient("https://cpp-hse.net", os.Getenv("NOTMANYTASK_TOKEN"))
+	if err != nil {
+		return err
+	}
+
+	err = nmt.RevokeToken(name)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Revoked token", zap.String("name", name))
+
+	return nil
+}
@@ -21,6 +21,7 @@ import (
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/semaphore"
 
 	"github.com/bigredeye/notmanytask/api"
@@ -55,13 +56,18 @@ type flagFetcher struct {
 
 func (f flagFetcher) doFetchFlag(task string) (string, error) {
 	buf, err := json.Marshal(&api.FlagRequest{
-		Token: f.token,
-		Task:  task,
+		Task: task,
 	})
 	if err != nil {
 		return "", err
 	}
-	res, err := http.Post(f.url, "application/json", bytes.NewReader(buf))
+	req, err := http.NewRequest(http.MethodPost, f.url, bytes.NewReader(buf))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		log.Printf("Failed to send flag request: %+v\n", err)
 		return "", err
@@ -107,6 +113,7 @@ func main() {
 	binariesDirectory := flag.String("build", "", "Path to build directory")
 	submitsDirectory := flag.String("submits", "", "Path to directory to store submits")
 	concurrencyLevel := flag.Int64("concurrency", 16, "Max number of computation-heavy tasks to run")
+	metricsAddress := flag.String("metrics-address", ":9091", "Address to serve prometheus metrics on, empty to disable")
 	flag.Parse()
 
 	checker, err := newChecker(*binariesDirectory, *submitsDirectory, *concurrencyLevel, os.Getenv("CRASHME_URL"), os.Getenv("CRASHME_TOKEN"))
@@ -120,6 +127,14 @@ func main() {
 	}
 	defer func() { _ = listener.Close() }()
 
+	if *metricsAddress != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			log.Printf("Metrics server failed: %+v", http.ListenAndServe(*metricsAddress, mux))
+		}()
+	}
+
 	acceptErrorsBudget := 10
 	currentAcceptErrorsBudget := acceptErrorsBudget
 	connID := 0
@@ -174,7 +189,9 @@ func newChecker(binariesDirectory, submitsDirectory string, concurrencyLevel int
 }
 
 func (c *checker) handleConnection(ctx context.Context, conn net.Conn, connID int) {
+	activeConnections.Inc()
 	defer func() {
+		activeConnections.Dec()
 		_ = conn.Close()
 		log.Printf("Closed connection #%d from %s\n", connID, conn.RemoteAddr())
 	}()
@@ -216,6 +233,7 @@ func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
 	ctx, cancel := context.WithTimeout(ctx, time.Minute) // FIXME(BigRedEye): Timeout from config
 	defer cancel()
 
+	waitStarted := time.Now()
 	if !c.sema.TryAcquire(1) {
 		writeStringOrIgnore(conn, "Waiting for an available runner...\n")
 		err := c.sema.Acquire(ctx, 1)
@@ -223,6 +241,7 @@ func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
 			return fmt.Errorf("failed to acquire semaphore: %w", err)
 		}
 	}
+	semaphoreWait.Observe(time.Since(waitStarted).Seconds())
 	defer c.sema.Release(1)
 
 	writeStringOrIgnore(conn, "Enter task name: ")
@@ -285,6 +304,7 @@ func (c *checker) doHandleConnection(ctx context.Context, conn net.Conn) error {
 			}
 
 			writeStringOrIgnore(conn, flag+"\n")
+			flagsIssued.Inc()
 			return nil
 		} else {
 			log.Printf("Failed to run command %s: %s", executablePath, stderrBuffer)
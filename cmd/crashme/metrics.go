@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	activeConnections = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "crashme",
+		Name:      "active_connections",
+		Help:      "Number of connections being handled.",
+	})
+
+	semaphoreWait = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "crashme",
+		Name:      "semaphore_wait_seconds",
+		Help:      "Time spent waiting for an available runner.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	})
+
+	flagsIssued = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "crashme",
+		Name:      "flags_issued_total",
+		Help:      "Flags sent to the users whose submits crashed the task.",
+	})
+)
@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	_ "github.com/joho/godotenv/autoload"
 
@@ -9,6 +10,12 @@ import (
 )
 
 func run() (err error) {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		return web.Migrate(os.Args[2:])
+	}
+	if len(os.Args) > 1 && os.Args[1] == "token" {
+		return web.CreateToken(os.Args[2:])
+	}
 	return web.Run()
 }
 
@@ -36,6 +36,8 @@ type EndpointsConfig struct {
 	Signup           string
 	Standings        string
 	Retakes          string
+	Admin            string
+	Events           string
 	OauthCallback    string
 	TelegramLogin    string
 	TelegramCallback string
@@ -44,17 +46,29 @@ type EndpointsConfig struct {
 		Report           string
 		Flag             string
 		Override         string
+		Extension        string
 		ChangeGroup      string
+		Retake           string
 		Standings        string
+		StandingsExport  string
 		ListGroupMembers string
+		DeadlinesStatus  string
+		Audit            string
+		Role             string
+		Tokens           string
 	}
 }
 
 type ServerConfig struct {
 	ListenAddress string
-	CourseName    string
-	HeaderName    string
-	Cookies       struct {
+	// MetricsAddress serves prometheus metrics apart from the public listener, empty disables metrics
+	MetricsAddress string
+	// AllowBodyTokens accepts API tokens from the request body for graders predating the Authorization header.
+	// Deprecated: graders should send the Authorization header, body tokens will be removed.
+	AllowBodyTokens bool
+	CourseName      string
+	HeaderName      string
+	Cookies         struct {
 		AuthenticationKey string
 		EncryptionKey     string
 	}
@@ -66,10 +80,8 @@ type DataBaseConfig struct {
 	User string
 	Pass string
 	Name string
-}
-
-type TestingConfig struct {
-	Tokens []string
+	// Driver is either postgres (default) or memory, the latter loses everything on restart
+	Driver string
 }
 
 type GroupConfig struct {
@@ -77,12 +89,20 @@ type GroupConfig struct {
 	Secret          string
 	DeadlinesURL    string
 	DeadlinesFormat string
-	ShowMarks       bool
-	Default         bool
+	// Timezone is used if deadlines do not specify their own one
+	Timezone  string
+	ShowMarks bool
+	Default   bool
 }
 
 type GroupsConfig []GroupConfig
 
+// RolesConfig grants roles by gitlab login in addition to the roles stored in the database.
+type RolesConfig struct {
+	Admins []string
+	TAs    []string
+}
+
 func (g GroupsConfig) FindGroup(name string) *GroupConfig {
 	for i := range g {
 		if g[i].Name == name {
@@ -110,6 +130,8 @@ type PullIntervalsConfig struct {
 	Deadlines time.Duration
 	Projects  *time.Duration
 	Pipelines *time.Duration
+	// Scores enables materialized scores updated with the given interval
+	Scores *time.Duration
 }
 
 type TelegramBotConfig struct {
@@ -123,10 +145,11 @@ type Config struct {
 	Endpoints     EndpointsConfig
 	Server        ServerConfig
 	DataBase      DataBaseConfig
-	Testing       TestingConfig
 	Groups        GroupsConfig
 	PullIntervals PullIntervalsConfig
 	Telegram      *TelegramBotConfig
+	// Roles are applied on top of the roles assigned via API
+	Roles RolesConfig
 }
 
 func ParseConfig() (*Config, error) {
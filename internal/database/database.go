@@ -3,6 +3,7 @@ package database
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -47,7 +48,21 @@ func isUnqiueViolation(err error) bool {
 	return false
 }
 
+// OpenDataBase connects to the database and applies pending migrations.
 func OpenDataBase(logger *zap.Logger, dsn string) (*DataBase, error) {
+	db, err := ConnectDataBase(logger, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = db.MigrateTo(LatestSchemaVersion, MigrateOptions{}); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// ConnectDataBase connects to the database without touching the schema, see MigrateTo.
+func ConnectDataBase(logger *zap.Logger, dsn string) (*DataBase, error) {
 	zapLogger := zapgorm2.New(logger.Named("gorm"))
 	zapLogger.SetAsDefault()
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
@@ -56,12 +71,6 @@ func OpenDataBase(logger *zap.Logger, dsn string) (*DataBase, error) {
 	if err != nil {
 		return nil, err
 	}
-
-	err = db.AutoMigrate(&models.User{}, &models.Pipeline{}, &models.Session{}, &models.Flag{}, &models.OverriddenScore{})
-	if err != nil {
-		return nil, err
-	}
-
 	return &DataBase{db}, nil
 }
 
@@ -177,8 +186,32 @@ func (db *DataBase) SetUserTelegramID(user *models.User) error {
 	return nil
 }
 
-func (db *DataBase) SetUserGroupName(user *models.User) error {
-	res := db.Model(user).Update("group_name", user.GroupName)
+func (db *DataBase) SetUserGroupName(user *models.User, audit *models.AuditRecord) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(user).Update("group_name", user.GroupName)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected < 1 {
+			return fmt.Errorf("unknown user %d", user.ID)
+		}
+		return addAuditRecord(tx, audit)
+	})
+}
+
+func (db *DataBase) SetUserRetakePolicy(user *models.User) error {
+	res := db.Model(user).Update("retake_policy", user.RetakePolicy)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return fmt.Errorf("unknown user %d", user.ID)
+	}
+	return nil
+}
+
+func (db *DataBase) SetUserRole(user *models.User) error {
+	res := db.Model(user).Update("role", user.Role)
 	if res.Error != nil {
 		return res.Error
 	}
@@ -188,13 +221,56 @@ func (db *DataBase) SetUserGroupName(user *models.User) error {
 	return nil
 }
 
+func (db *DataBase) SearchUsers(query string) ([]*models.User, error) {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(query) + "%"
+	var users []*models.User
+	err := db.Order("last_name, first_name").Find(&users,
+		"first_name ILIKE ? OR last_name ILIKE ? OR gitlab_login ILIKE ? OR first_name || ' ' || last_name ILIKE ?",
+		pattern, pattern, pattern, pattern,
+	).Error
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// AddPipeline does not touch partial credit of the pipeline, see SetPipelineCredit.
+// Pipelines are refetched periodically, so unchanged pipelines are not updated at all.
 func (db *DataBase) AddPipeline(pipeline *models.Pipeline) error {
 	return db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"status"}),
+		DoUpdates: clause.AssignmentColumns([]string{"status", "task", "project", "started_at", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+			SQL: "pipelines.status IS DISTINCT FROM excluded.status OR " +
+				"pipelines.task IS DISTINCT FROM excluded.task OR " +
+				"pipelines.project IS DISTINCT FROM excluded.project OR " +
+				"pipelines.started_at IS DISTINCT FROM excluded.started_at",
+		}}},
 	}).Create(pipeline).Error
 }
 
+// SetPipelineCredit stores partial credit reported by the grader.
+// Grader may report before the pipeline is fetched from gitlab, so the pipeline is created if needed.
+func (db *DataBase) SetPipelineCredit(pipeline *models.Pipeline) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fraction", "tests_passed", "tests_total", "updated_at"}),
+	}).Create(creditPipeline(pipeline)).Error
+}
+
+// creditPipeline fills the fields of a pipeline reported by the grader before gitlab.
+// The grader reports from a running pipeline, AddPipeline overwrites them with the actual values later.
+func creditPipeline(pipeline *models.Pipeline) *models.Pipeline {
+	created := *pipeline
+	if created.Status == "" {
+		created.Status = models.PipelineStatusRunning
+	}
+	if created.StartedAt.IsZero() {
+		created.StartedAt = time.Now()
+	}
+	return &created
+}
+
 func (db *DataBase) ListProjectPipelines(project string) (pipelines []models.Pipeline, err error) {
 	pipelines = make([]models.Pipeline, 0)
 	err = db.Find(&pipelines, "project = ?", project).Error
@@ -263,7 +339,10 @@ func (db *DataBase) CreateFlag(task string) (*models.Flag, error) {
 }
 
 func (db *DataBase) SubmitFlag(id, gitlabLogin string) error {
-	result := db.Model(&models.Flag{}).Where("id = ? AND gitlab_login IS NULL", id).Update("gitlab_login", gitlabLogin)
+	result := db.Model(&models.Flag{}).Where("id = ? AND gitlab_login IS NULL", id).Updates(map[string]interface{}{
+		"gitlab_login": gitlabLogin,
+		"submitted_at": time.Now(),
+	})
 	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 		return fmt.Errorf("unknown flag")
 	}
@@ -312,22 +391,255 @@ func (db *DataBase) ListOverrides() (overrides []models.OverriddenScore, err err
 	return
 }
 
-func (db *DataBase) AddOverride(gitlabLogin, task string, score int, status models.PipelineStatus) error {
+func (db *DataBase) AddOverride(gitlabLogin, task string, score int, status models.PipelineStatus, audit *models.AuditRecord) error {
 	overridenScore := &models.OverriddenScore{
 		GitlabLogin: gitlabLogin,
 		Task:        task,
 		Score:       score,
 		Status:      status,
 	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "gitlab_login"}, {Name: "task"}},
+			DoUpdates: clause.AssignmentColumns([]string{"score", "status", "updated_at", "deleted_at"}),
+		}).Create(overridenScore).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&models.OverrideHistory{
+			GitlabLogin: gitlabLogin,
+			Task:        task,
+			Score:       score,
+			Status:      status,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return addAuditRecord(tx, audit)
+	})
+}
+
+func (db *DataBase) RemoveOverride(gitlabLogin, task string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		res := tx.
+			Where("gitlab_login = ? AND task = ?", gitlabLogin, task).
+			Delete(models.OverriddenScore{})
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return tx.Create(&models.OverrideHistory{
+			GitlabLogin: gitlabLogin,
+			Task:        task,
+			Removed:     true,
+		}).Error
+	})
+}
+
+// ListOverridesAt restores overrides as they were at the given moment.
+func (db *DataBase) ListOverridesAt(at time.Time) (overrides []models.OverriddenScore, err error) {
+	history := make([]models.OverrideHistory, 0)
+	err = db.Where("created_at <= ?", at).Order("created_at, id").Find(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return restoreOverrides(history), nil
+}
+
+// restoreOverrides replays the override history ordered by creation time.
+func restoreOverrides(history []models.OverrideHistory) []models.OverriddenScore {
+	type key struct {
+		login string
+		task  string
+	}
+	latest := make(map[key]*models.OverrideHistory)
+	for i := range history {
+		latest[key{history[i].GitlabLogin, history[i].Task}] = &history[i]
+	}
+
+	overrides := make([]models.OverriddenScore, 0, len(latest))
+	for _, record := range latest {
+		if record.Removed {
+			continue
+		}
+		overrides = append(overrides, models.OverriddenScore{
+			GitlabLogin: record.GitlabLogin,
+			Task:        record.Task,
+			Score:       record.Score,
+			Status:      record.Status,
+		})
+	}
+	return overrides
+}
+
+func (db *DataBase) ListUserExtensions(login string) (extensions []models.Extension, err error) {
+	extensions = make([]models.Extension, 0)
+	err = db.Find(&extensions, "gitlab_login = ?", login).Error
+	if err != nil {
+		extensions = nil
+	}
+	return
+}
+
+func (db *DataBase) ListExtensions() (extensions []models.Extension, err error) {
+	extensions = make([]models.Extension, 0)
+	err = db.Find(&extensions).Error
+	if err != nil {
+		extensions = nil
+	}
+	return
+}
+
+func (db *DataBase) AddExtension(gitlabLogin, task string, deadline time.Time, reason string) error {
+	extension := &models.Extension{
+		GitlabLogin: gitlabLogin,
+		Task:        task,
+		Deadline:    deadline,
+		Reason:      reason,
+	}
 	return db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "gitlab_login"}, {Name: "task"}},
-		DoUpdates: clause.AssignmentColumns([]string{"score", "status"}),
-	}).Create(overridenScore).Error
+		DoUpdates: clause.AssignmentColumns([]string{"deadline", "reason", "updated_at", "deleted_at"}),
+	}).Create(extension).Error
 }
 
-func (db *DataBase) RemoveOverride(gitlabLogin, task string) error {
+func (db *DataBase) RemoveExtension(gitlabLogin, task string) error {
 	return db.
 		Where("gitlab_login = ? AND task = ?", gitlabLogin, task).
-		Delete(models.OverriddenScore{}).
+		Delete(models.Extension{}).
 		Error
 }
+
+func (db *DataBase) SaveMaterializedScores(scores *models.MaterializedScores) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "gitlab_login"}},
+		DoUpdates: clause.AssignmentColumns([]string{"group_name", "scores", "updated_at"}),
+	}).Create(scores).Error
+}
+
+func (db *DataBase) FindMaterializedScores(login string) (*models.MaterializedScores, error) {
+	var scores models.MaterializedScores
+	err := db.First(&scores, "gitlab_login = ?", login).Error
+	if err != nil {
+		return nil, err
+	}
+	return &scores, nil
+}
+
+func (db *DataBase) ListMaterializedScores(groupName string) (scores []models.MaterializedScores, err error) {
+	scores = make([]models.MaterializedScores, 0)
+	err = db.Find(&scores, "group_name = ?", groupName).Error
+	if err != nil {
+		scores = nil
+	}
+	return
+}
+
+// ListProjectsChangedSince returns projects with pipelines added or changed after the given moment.
+func (db *DataBase) ListProjectsChangedSince(since time.Time) (projects []string, err error) {
+	projects = make([]string, 0)
+	err = db.Model(&models.Pipeline{}).Distinct().Where("updated_at > ?", since).Pluck("project", &projects).Error
+	if err != nil {
+		projects = nil
+	}
+	return
+}
+
+// ListLoginsChangedSince returns logins of the users with flags, overrides, extensions or profile changed after the given moment.
+func (db *DataBase) ListLoginsChangedSince(since time.Time) ([]string, error) {
+	queries := []*gorm.DB{
+		db.Model(&models.Flag{}).Where("submitted_at > ?", since),
+		db.Model(&models.OverrideHistory{}).Where("created_at > ?", since),
+		db.Unscoped().Model(&models.Extension{}).Where("updated_at > ? OR deleted_at > ?", since, since),
+		db.Model(&models.User{}).Where("updated_at > ? AND gitlab_login IS NOT NULL", since),
+	}
+
+	logins := make([]string, 0)
+	for _, query := range queries {
+		changed := make([]string, 0)
+		if err := query.Distinct().Pluck("gitlab_login", &changed).Error; err != nil {
+			return nil, err
+		}
+		logins = append(logins, changed...)
+	}
+	return logins, nil
+}
+
+func (db *DataBase) AddAuditRecord(record *models.AuditRecord) error {
+	return db.Create(record).Error
+}
+
+// addAuditRecord writes the record as a part of the change made in the transaction.
+func addAuditRecord(tx *gorm.DB, record *models.AuditRecord) error {
+	if record == nil {
+		return nil
+	}
+	return tx.Create(record).Error
+}
+
+// ListAuditRecords returns matching records, the latest first.
+func (db *DataBase) ListAuditRecords(filter AuditFilter) (records []models.AuditRecord, err error) {
+	query := db.Order("created_at DESC, id DESC")
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Target != "" {
+		query = query.Where("target = ?", filter.Target)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	records = make([]models.AuditRecord, 0)
+	err = query.Find(&records).Error
+	if err != nil {
+		records = nil
+	}
+	return
+}
+
+func (db *DataBase) CreateAPIToken(token *models.APIToken) error {
+	err := db.Create(token).Error
+	if isUnqiueViolation(err) {
+		return &DuplicateKey{err}
+	}
+	return err
+}
+
+func (db *DataBase) FindAPIToken(hash string) (*models.APIToken, error) {
+	var token models.APIToken
+	err := db.First(&token, "hash = ?", hash).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (db *DataBase) ListAPITokens() (tokens []models.APIToken, err error) {
+	tokens = make([]models.APIToken, 0)
+	err = db.Order("name").Find(&tokens).Error
+	if err != nil {
+		tokens = nil
+	}
+	return
+}
+
+func (db *DataBase) RevokeAPIToken(name string, at time.Time) error {
+	res := db.Model(&models.APIToken{}).Where("name = ? AND revoked_at IS NULL", name).Update("revoked_at", at)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected < 1 {
+		return fmt.Errorf("unknown token %s", name)
+	}
+	return nil
+}
+
+func (db *DataBase) TouchAPIToken(id uint, at time.Time) error {
+	return db.Model(&models.APIToken{}).Where("id = ?", id).Update("last_used_at", at).Error
+}
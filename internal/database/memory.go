@@ -0,0 +1,761 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+var errMemoryUniqueViolation = errors.New("duplicate key value violates unique constraint")
+
+// MemoryStore keeps everything in memory and loses it on restart.
+// It mimics DataBase closely enough for tests and single binary deployments of small courses.
+type MemoryStore struct {
+	mu sync.Mutex
+
+	users      []*models.User
+	pipelines  map[int]*models.Pipeline
+	sessions   []*models.Session
+	flags      map[string]*models.Flag
+	overrides  []*models.OverriddenScore
+	history    []models.OverrideHistory
+	extensions []*models.Extension
+	scores     map[string]*models.MaterializedScores
+	audit      []models.AuditRecord
+	tokens     []*models.APIToken
+
+	lastID uint
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pipelines: make(map[int]*models.Pipeline),
+		flags:     make(map[string]*models.Flag),
+		scores:    make(map[string]*models.MaterializedScores),
+	}
+}
+
+func (m *MemoryStore) nextID() uint {
+	m.lastID++
+	return m.lastID
+}
+
+func equalPtr[T comparable](left, right *T) bool {
+	if left == nil || right == nil {
+		return left == right
+	}
+	return *left == *right
+}
+
+func copyUser(user *models.User) *models.User {
+	res := *user
+	return &res
+}
+
+// matchesUser mimics gorm conditions built from the struct: zero fields are ignored.
+func matchesUser(user, cond *models.User) bool {
+	return (cond.ID == 0 || user.ID == cond.ID) &&
+		(cond.FirstName == "" || user.FirstName == cond.FirstName) &&
+		(cond.LastName == "" || user.LastName == cond.LastName) &&
+		(cond.GroupName == "" || user.GroupName == cond.GroupName) &&
+		(cond.GitlabID == nil || equalPtr(user.GitlabID, cond.GitlabID)) &&
+		(cond.GitlabLogin == nil || equalPtr(user.GitlabLogin, cond.GitlabLogin)) &&
+		(cond.TelegramID == nil || equalPtr(user.TelegramID, cond.TelegramID))
+}
+
+// conflictsWith checks the unique indices of the users table.
+func conflictsWith(user, other *models.User) bool {
+	if user.ID == other.ID {
+		return false
+	}
+	return (user.FirstName == other.FirstName && user.LastName == other.LastName && user.GroupName == other.GroupName) ||
+		(user.GitlabID != nil && equalPtr(user.GitlabID, other.GitlabID)) ||
+		(user.GitlabLogin != nil && equalPtr(user.GitlabLogin, other.GitlabLogin))
+}
+
+func (m *MemoryStore) findUser(pred func(user *models.User) bool) (*models.User, error) {
+	for _, user := range m.users {
+		if pred(user) {
+			return user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryStore) AddUser(user *models.User) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if found, err := m.findUser(func(other *models.User) bool { return matchesUser(other, user) }); err == nil {
+		return copyUser(found), nil
+	}
+
+	created := copyUser(user)
+	for _, other := range m.users {
+		if conflictsWith(created, other) {
+			return nil, &DuplicateKey{errMemoryUniqueViolation}
+		}
+	}
+	created.ID = m.nextID()
+	created.CreatedAt = time.Now()
+	created.UpdatedAt = created.CreatedAt
+	m.users = append(m.users, created)
+	return copyUser(created), nil
+}
+
+func (m *MemoryStore) FindUserByID(id uint) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.findUser(func(user *models.User) bool { return user.ID == id })
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(user), nil
+}
+
+func (m *MemoryStore) FindUserByGitlabLogin(login string) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.findUser(func(user *models.User) bool { return equalPtr(user.GitlabLogin, &login) })
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(user), nil
+}
+
+func (m *MemoryStore) FindUserByGitlabID(id int) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.findUser(func(user *models.User) bool { return equalPtr(user.GitlabID, &id) })
+	if err != nil {
+		return nil, err
+	}
+	return copyUser(user), nil
+}
+
+func (m *MemoryStore) FindUserByTelegramID(id int64) (*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user, err := m.findUser(func(user *models.User) bool { return equalPtr(user.TelegramID, &id) })
+	if err != nil {
+		return nil, nil
+	}
+	return copyUser(user), nil
+}
+
+func (m *MemoryStore) listUsers(pred func(user *models.User) bool) []*models.User {
+	users := make([]*models.User, 0)
+	for _, user := range m.users {
+		if pred(user) {
+			users = append(users, copyUser(user))
+		}
+	}
+	return users
+}
+
+func (m *MemoryStore) ListUsersWithoutRepos() ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listUsers(func(user *models.User) bool {
+		return user.Repository == nil && user.GitlabID != nil && user.GitlabLogin != nil
+	}), nil
+}
+
+func (m *MemoryStore) ListGroupUsers(groupName string) ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listUsers(func(user *models.User) bool {
+		return user.Repository != nil && user.GroupName == groupName
+	}), nil
+}
+
+func (m *MemoryStore) SetUserGitlabAccount(uid uint, user *models.GitlabUser) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	found, err := m.findUser(func(other *models.User) bool {
+		return other.ID == uid && (other.GitlabID == nil || other.GitlabLogin == nil)
+	})
+	if err != nil {
+		return fmt.Errorf("unknown user %d", uid)
+	}
+
+	updated := copyUser(found)
+	updated.GitlabID = user.GitlabID
+	updated.GitlabLogin = user.GitlabLogin
+	for _, other := range m.users {
+		if conflictsWith(updated, other) {
+			return &DuplicateKey{errMemoryUniqueViolation}
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	*found = *updated
+	return nil
+}
+
+func (m *MemoryStore) updateUser(user *models.User, update func(stored *models.User)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, err := m.findUser(func(other *models.User) bool { return other.ID == user.ID })
+	if err != nil {
+		return fmt.Errorf("unknown user %d", user.ID)
+	}
+	update(stored)
+	stored.UpdatedAt = time.Now()
+	user.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (m *MemoryStore) SetUserRepository(user *models.User) error {
+	return m.updateUser(user, func(stored *models.User) { stored.Repository = user.Repository })
+}
+
+func (m *MemoryStore) SetUserTelegramID(user *models.User) error {
+	return m.updateUser(user, func(stored *models.User) { stored.TelegramID = user.TelegramID })
+}
+
+func (m *MemoryStore) SetUserGroupName(user *models.User, audit *models.AuditRecord) error {
+	return m.updateUser(user, func(stored *models.User) {
+		stored.GroupName = user.GroupName
+		m.addAuditRecord(audit)
+	})
+}
+
+func (m *MemoryStore) SetUserRetakePolicy(user *models.User) error {
+	return m.updateUser(user, func(stored *models.User) { stored.RetakePolicy = user.RetakePolicy })
+}
+
+func (m *MemoryStore) SetUserRole(user *models.User) error {
+	return m.updateUser(user, func(stored *models.User) { stored.Role = user.Role })
+}
+
+func (m *MemoryStore) SearchUsers(query string) ([]*models.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = strings.ToLower(query)
+	users := m.listUsers(func(user *models.User) bool {
+		login := ""
+		if user.GitlabLogin != nil {
+			login = *user.GitlabLogin
+		}
+		for _, field := range []string{user.FirstName, user.LastName, login, user.FirstName + " " + user.LastName} {
+			if strings.Contains(strings.ToLower(field), query) {
+				return true
+			}
+		}
+		return false
+	})
+	sort.SliceStable(users, func(i, j int) bool {
+		if users[i].LastName != users[j].LastName {
+			return users[i].LastName < users[j].LastName
+		}
+		return users[i].FirstName < users[j].FirstName
+	})
+	return users, nil
+}
+
+func (m *MemoryStore) AddPipeline(pipeline *models.Pipeline) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, found := m.pipelines[pipeline.ID]
+	if !found {
+		created := *pipeline
+		created.UpdatedAt = time.Now()
+		m.pipelines[pipeline.ID] = &created
+		return nil
+	}
+	if stored.Status == pipeline.Status && stored.Task == pipeline.Task &&
+		stored.Project == pipeline.Project && stored.StartedAt.Equal(pipeline.StartedAt) {
+		return nil
+	}
+	stored.Status = pipeline.Status
+	stored.Task = pipeline.Task
+	stored.Project = pipeline.Project
+	stored.StartedAt = pipeline.StartedAt
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) SetPipelineCredit(pipeline *models.Pipeline) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, found := m.pipelines[pipeline.ID]
+	if !found {
+		created := creditPipeline(pipeline)
+		created.UpdatedAt = time.Now()
+		m.pipelines[pipeline.ID] = created
+		return nil
+	}
+	stored.Fraction = pipeline.Fraction
+	stored.TestsPassed = pipeline.TestsPassed
+	stored.TestsTotal = pipeline.TestsTotal
+	stored.UpdatedAt = time.Now()
+	return nil
+}
+
+func (m *MemoryStore) listPipelines(pred func(pipeline *models.Pipeline) bool) []models.Pipeline {
+	pipelines := make([]models.Pipeline, 0)
+	for _, pipeline := range m.pipelines {
+		if pred(pipeline) {
+			pipelines = append(pipelines, *pipeline)
+		}
+	}
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i].ID < pipelines[j].ID
+	})
+	return pipelines
+}
+
+func (m *MemoryStore) ListProjectPipelines(project string) ([]models.Pipeline, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listPipelines(func(pipeline *models.Pipeline) bool { return pipeline.Project == project }), nil
+}
+
+func (m *MemoryStore) ListAllPipelines() ([]models.Pipeline, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listPipelines(func(pipeline *models.Pipeline) bool { return true }), nil
+}
+
+func (m *MemoryStore) CreateSession(user uint) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session := &models.Session{
+		ID:     m.nextID(),
+		Token:  uuid.Must(uuid.NewUUID()).String(),
+		UserID: user,
+	}
+	m.sessions = append(m.sessions, session)
+	res := *session
+	return &res, nil
+}
+
+func (m *MemoryStore) FindSession(token string) (*models.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, session := range m.sessions {
+		if session.Token == token {
+			res := *session
+			return &res, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryStore) FindUserBySession(token string) (*models.User, *models.Session, error) {
+	session, err := m.FindSession(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	user, err := m.FindUserByID(session.UserID)
+	if err != nil {
+		return nil, session, err
+	}
+	return user, session, nil
+}
+
+func (m *MemoryStore) CreateFlag(task string) (*models.Flag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag := &models.Flag{
+		ID:        fmt.Sprintf("{FLAG-%s-%s}", task, uuid.New().String()),
+		Task:      task,
+		CreatedAt: time.Now(),
+	}
+	m.flags[flag.ID] = flag
+	res := *flag
+	return &res, nil
+}
+
+func (m *MemoryStore) SubmitFlag(id, gitlabLogin string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag, found := m.flags[id]
+	if !found || flag.GitlabLogin != nil {
+		return fmt.Errorf("unknown flag")
+	}
+	submittedAt := time.Now()
+	flag.GitlabLogin = &gitlabLogin
+	flag.SubmittedAt = &submittedAt
+	return nil
+}
+
+func (m *MemoryStore) listFlags(pred func(flag *models.Flag) bool) []models.Flag {
+	flags := make([]models.Flag, 0)
+	for _, flag := range m.flags {
+		if pred(flag) {
+			flags = append(flags, *flag)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].CreatedAt.Before(flags[j].CreatedAt)
+	})
+	return flags
+}
+
+func (m *MemoryStore) ListUserFlags(gitlabLogin string) ([]models.Flag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listFlags(func(flag *models.Flag) bool { return equalPtr(flag.GitlabLogin, &gitlabLogin) }), nil
+}
+
+func (m *MemoryStore) ListSubmittedFlags() ([]models.Flag, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listFlags(func(flag *models.Flag) bool { return flag.GitlabLogin != nil }), nil
+}
+
+func (m *MemoryStore) listOverrides(pred func(override *models.OverriddenScore) bool) []models.OverriddenScore {
+	overrides := make([]models.OverriddenScore, 0)
+	for _, override := range m.overrides {
+		if !override.DeletedAt.Valid && pred(override) {
+			overrides = append(overrides, *override)
+		}
+	}
+	return overrides
+}
+
+func (m *MemoryStore) ListUserOverrides(login string) ([]models.OverriddenScore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listOverrides(func(override *models.OverriddenScore) bool { return override.GitlabLogin == login }), nil
+}
+
+func (m *MemoryStore) ListOverrides() ([]models.OverriddenScore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listOverrides(func(override *models.OverriddenScore) bool { return true }), nil
+}
+
+func (m *MemoryStore) addHistory(record models.OverrideHistory) {
+	record.ID = m.nextID()
+	record.CreatedAt = time.Now()
+	m.history = append(m.history, record)
+}
+
+func (m *MemoryStore) AddOverride(gitlabLogin, task string, score int, status models.PipelineStatus, audit *models.AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var stored *models.OverriddenScore
+	for _, override := range m.overrides {
+		if override.GitlabLogin == gitlabLogin && override.Task == task {
+			stored = override
+			break
+		}
+	}
+	if stored == nil {
+		stored = &models.OverriddenScore{GitlabLogin: gitlabLogin, Task: task}
+		stored.ID = m.nextID()
+		stored.CreatedAt = now
+		m.overrides = append(m.overrides, stored)
+	}
+	stored.Score = score
+	stored.Status = status
+	stored.UpdatedAt = now
+	stored.DeletedAt = gorm.DeletedAt{}
+
+	m.addHistory(models.OverrideHistory{
+		GitlabLogin: gitlabLogin,
+		Task:        task,
+		Score:       score,
+		Status:      status,
+	})
+	m.addAuditRecord(audit)
+	return nil
+}
+
+func (m *MemoryStore) RemoveOverride(gitlabLogin, task string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, override := range m.overrides {
+		if override.GitlabLogin == gitlabLogin && override.Task == task && !override.DeletedAt.Valid {
+			override.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+			m.addHistory(models.OverrideHistory{
+				GitlabLogin: gitlabLogin,
+				Task:        task,
+				Removed:     true,
+			})
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) ListOverridesAt(at time.Time) ([]models.OverriddenScore, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// History is appended in the creation order
+	history := make([]models.OverrideHistory, 0, len(m.history))
+	for _, record := range m.history {
+		if !record.CreatedAt.After(at) {
+			history = append(history, record)
+		}
+	}
+	return restoreOverrides(history), nil
+}
+
+func (m *MemoryStore) listExtensions(pred func(extension *models.Extension) bool) []models.Extension {
+	extensions := make([]models.Extension, 0)
+	for _, extension := range m.extensions {
+		if !extension.DeletedAt.Valid && pred(extension) {
+			extensions = append(extensions, *extension)
+		}
+	}
+	return extensions
+}
+
+func (m *MemoryStore) ListUserExtensions(login string) ([]models.Extension, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listExtensions(func(extension *models.Extension) bool { return extension.GitlabLogin == login }), nil
+}
+
+func (m *MemoryStore) ListExtensions() ([]models.Extension, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listExtensions(func(extension *models.Extension) bool { return true }), nil
+}
+
+func (m *MemoryStore) AddExtension(gitlabLogin, task string, deadline time.Time, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, extension := range m.extensions {
+		if extension.GitlabLogin == gitlabLogin && extension.Task == task {
+			extension.Deadline = deadline
+			extension.Reason = reason
+			extension.UpdatedAt = now
+			extension.DeletedAt = gorm.DeletedAt{}
+			return nil
+		}
+	}
+
+	extension := &models.Extension{
+		GitlabLogin: gitlabLogin,
+		Task:        task,
+		Deadline:    deadline,
+		Reason:      reason,
+	}
+	extension.ID = m.nextID()
+	extension.CreatedAt = now
+	extension.UpdatedAt = now
+	m.extensions = append(m.extensions, extension)
+	return nil
+}
+
+func (m *MemoryStore) RemoveExtension(gitlabLogin, task string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, extension := range m.extensions {
+		if extension.GitlabLogin == gitlabLogin && extension.Task == task && !extension.DeletedAt.Valid {
+			extension.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) SaveMaterializedScores(scores *models.MaterializedScores) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored := *scores
+	stored.UpdatedAt = time.Now()
+	m.scores[scores.GitlabLogin] = &stored
+	return nil
+}
+
+func (m *MemoryStore) FindMaterializedScores(login string) (*models.MaterializedScores, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, found := m.scores[login]
+	if !found {
+		return nil, gorm.ErrRecordNotFound
+	}
+	res := *stored
+	return &res, nil
+}
+
+func (m *MemoryStore) ListMaterializedScores(groupName string) ([]models.MaterializedScores, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	scores := make([]models.MaterializedScores, 0)
+	for _, stored := range m.scores {
+		if stored.GroupName == groupName {
+			scores = append(scores, *stored)
+		}
+	}
+	return scores, nil
+}
+
+func (m *MemoryStore) ListProjectsChangedSince(since time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seen := make(map[string]bool)
+	projects := make([]string, 0)
+	for _, pipeline := range m.pipelines {
+		if pipeline.UpdatedAt.After(since) && !seen[pipeline.Project] {
+			seen[pipeline.Project] = true
+			projects = append(projects, pipeline.Project)
+		}
+	}
+	return projects, nil
+}
+
+func (m *MemoryStore) ListLoginsChangedSince(since time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	logins := make([]string, 0)
+	for _, flag := range m.flags {
+		if flag.GitlabLogin != nil && flag.SubmittedAt != nil && flag.SubmittedAt.After(since) {
+			logins = append(logins, *flag.GitlabLogin)
+		}
+	}
+	for _, record := range m.history {
+		if record.CreatedAt.After(since) {
+			logins = append(logins, record.GitlabLogin)
+		}
+	}
+	for _, extension := range m.extensions {
+		if extension.UpdatedAt.After(since) || (extension.DeletedAt.Valid && extension.DeletedAt.Time.After(since)) {
+			logins = append(logins, extension.GitlabLogin)
+		}
+	}
+	for _, user := range m.users {
+		if user.GitlabLogin != nil && user.UpdatedAt.After(since) {
+			logins = append(logins, *user.GitlabLogin)
+		}
+	}
+	return logins, nil
+}
+
+func (m *MemoryStore) AddAuditRecord(record *models.AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.addAuditRecord(record)
+	return nil
+}
+
+// addAuditRecord expects the lock to be held, nil record is skipped.
+func (m *MemoryStore) addAuditRecord(record *models.AuditRecord) {
+	if record == nil {
+		return
+	}
+	record.ID = m.nextID()
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+	m.audit = append(m.audit, *record)
+}
+
+func (m *MemoryStore) ListAuditRecords(filter AuditFilter) ([]models.AuditRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	records := make([]models.AuditRecord, 0)
+	for i := len(m.audit) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(records) == filter.Limit {
+			break
+		}
+		if filter.matches(&m.audit[i]) {
+			records = append(records, m.audit[i])
+		}
+	}
+	return records, nil
+}
+
+func (m *MemoryStore) CreateAPIToken(token *models.APIToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.tokens {
+		if stored.Name == token.Name || stored.Hash == token.Hash {
+			return &DuplicateKey{errMemoryUniqueViolation}
+		}
+	}
+	token.ID = m.nextID()
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	stored := *token
+	m.tokens = append(m.tokens, &stored)
+	return nil
+}
+
+func (m *MemoryStore) FindAPIToken(hash string) (*models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.Hash == hash {
+			res := *token
+			return &res, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (m *MemoryStore) ListAPITokens() ([]models.APIToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tokens := make([]models.APIToken, 0, len(m.tokens))
+	for _, token := range m.tokens {
+		tokens = append(tokens, *token)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].Name < tokens[j].Name
+	})
+	return tokens, nil
+}
+
+func (m *MemoryStore) RevokeAPIToken(name string, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.Name == name && token.RevokedAt == nil {
+			token.RevokedAt = &at
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token %s", name)
+}
+
+func (m *MemoryStore) TouchAPIToken(id uint, at time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, token := range m.tokens {
+		if token.ID == id {
+			token.LastUsedAt = &at
+		}
+	}
+	return nil
+}
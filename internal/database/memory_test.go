@@ -0,0 +1,165 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func TestMemoryStoreUsers(t *testing.T) {
+	store := NewMemoryStore()
+
+	user, err := store.AddUser(&models.User{FirstName: "Ivan", LastName: "Ivanov", GroupName: "hse"})
+	if err != nil {
+		t.Fatal("Failed to add user:", err)
+	}
+	same, err := store.AddUser(&models.User{FirstName: "Ivan", LastName: "Ivanov", GroupName: "hse"})
+	if err != nil || same.ID != user.ID {
+		t.Fatalf("Expected existing user %d, got %v, %v", user.ID, same, err)
+	}
+
+	id, login := 42, "ivanov"
+	if err = store.SetUserGitlabAccount(user.ID, &models.GitlabUser{GitlabID: &id, GitlabLogin: &login}); err != nil {
+		t.Fatal("Failed to set gitlab account:", err)
+	}
+	other, err := store.AddUser(&models.User{FirstName: "Petr", LastName: "Petrov", GroupName: "hse"})
+	if err != nil {
+		t.Fatal("Failed to add user:", err)
+	}
+	err = store.SetUserGitlabAccount(other.ID, &models.GitlabUser{GitlabID: &id, GitlabLogin: &login})
+	if !IsDuplicateKey(err) {
+		t.Fatalf("Expected duplicate key, got %v", err)
+	}
+
+	if users, _ := store.ListGroupUsers("hse"); len(users) != 0 {
+		t.Fatalf("Users without repository are listed: %v", users)
+	}
+	repository := "hse/ivanov"
+	user.Repository = &repository
+	if err = store.SetUserRepository(user); err != nil {
+		t.Fatal("Failed to set repository:", err)
+	}
+	if users, _ := store.ListGroupUsers("hse"); len(users) != 1 || users[0].ID != user.ID {
+		t.Fatalf("Invalid group users: %v", users)
+	}
+
+	if found, err := store.FindUserByTelegramID(1); found != nil || err != nil {
+		t.Fatalf("Expected no user, got %v, %v", found, err)
+	}
+}
+
+func TestMemoryStoreOverrideHistory(t *testing.T) {
+	store := NewMemoryStore()
+
+	if err := store.AddOverride("ivanov", "sum", 100, models.PipelineStatusSuccess, nil); err != nil {
+		t.Fatal("Failed to add override:", err)
+	}
+	added := time.Now()
+	time.Sleep(time.Millisecond)
+	if err := store.RemoveOverride("ivanov", "sum"); err != nil {
+		t.Fatal("Failed to remove override:", err)
+	}
+
+	if overrides, _ := store.ListOverrides(); len(overrides) != 0 {
+		t.Fatalf("Removed override is listed: %v", overrides)
+	}
+	overrides, _ := store.ListOverridesAt(added)
+	if len(overrides) != 1 || overrides[0].Score != 100 {
+		t.Fatalf("Invalid restored overrides: %v", overrides)
+	}
+	if logins, _ := store.ListLoginsChangedSince(added); len(logins) != 1 || logins[0] != "ivanov" {
+		t.Fatalf("Invalid changed logins: %v", logins)
+	}
+}
+
+func TestMemoryStorePipelineChanges(t *testing.T) {
+	store := NewMemoryStore()
+	pipeline := models.Pipeline{ID: 1, Project: "ivanov", Task: "sum", Status: models.PipelineStatusRunning}
+
+	if err := store.AddPipeline(&pipeline); err != nil {
+		t.Fatal("Failed to add pipeline:", err)
+	}
+	added := time.Now()
+	time.Sleep(time.Millisecond)
+
+	if err := store.AddPipeline(&pipeline); err != nil {
+		t.Fatal("Failed to add pipeline:", err)
+	}
+	if projects, _ := store.ListProjectsChangedSince(added); len(projects) != 0 {
+		t.Fatalf("Unchanged pipeline is reported: %v", projects)
+	}
+
+	pipeline.Status = models.PipelineStatusSuccess
+	if err := store.AddPipeline(&pipeline); err != nil {
+		t.Fatal("Failed to add pipeline:", err)
+	}
+	if projects, _ := store.ListProjectsChangedSince(added); len(projects) != 1 || projects[0] != "ivanov" {
+		t.Fatalf("Invalid changed projects: %v", projects)
+	}
+}
+
+func TestMemoryStoreAudit(t *testing.T) {
+	store := NewMemoryStore()
+	for _, action := range []string{models.AuditActionOverride, models.AuditActionChangeGroup, models.AuditActionOverride} {
+		if err := store.AddAuditRecord(&models.AuditRecord{Actor: "token:1", Action: action, Target: "ivanov"}); err != nil {
+			t.Fatal("Failed to add audit record:", err)
+		}
+	}
+
+	records, _ := store.ListAuditRecords(AuditFilter{Action: models.AuditActionOverride})
+	if len(records) != 2 || records[0].ID < records[1].ID {
+		t.Fatalf("Invalid audit records: %v", records)
+	}
+	records, _ = store.ListAuditRecords(AuditFilter{Target: "ivanov", Limit: 1})
+	if len(records) != 1 || records[0].Action != models.AuditActionOverride {
+		t.Fatalf("Invalid latest audit record: %v", records)
+	}
+}
+
+func TestMemoryStoreAuditWithChange(t *testing.T) {
+	store := NewMemoryStore()
+	override := &models.AuditRecord{Actor: "token:1", Action: models.AuditActionOverride, Target: "ivanov"}
+	if err := store.AddOverride("ivanov", "sum", 100, models.PipelineStatusSuccess, override); err != nil {
+		t.Fatal("Failed to add override:", err)
+	}
+	changeGroup := &models.AuditRecord{Actor: "token:1", Action: models.AuditActionChangeGroup, Target: "petrov"}
+	if err := store.SetUserGroupName(&models.User{GroupName: "hse"}, changeGroup); err == nil {
+		t.Fatal("Expected unknown user to fail")
+	}
+
+	records, _ := store.ListAuditRecords(AuditFilter{})
+	if len(records) != 1 || records[0].Action != models.AuditActionOverride {
+		t.Fatalf("Invalid audit records: %v", records)
+	}
+}
+
+func TestMemoryStoreAPITokens(t *testing.T) {
+	store := NewMemoryStore()
+	token := &models.APIToken{Name: "grader", Hash: models.HashAPIToken("secret"), Scopes: "report,flag"}
+	if err := store.CreateAPIToken(token); err != nil {
+		t.Fatal("Failed to create token:", err)
+	}
+	if err := store.CreateAPIToken(&models.APIToken{Name: "grader", Hash: models.HashAPIToken("other")}); !IsDuplicateKey(err) {
+		t.Fatalf("Expected duplicate token name, got %v", err)
+	}
+
+	found, err := store.FindAPIToken(models.HashAPIToken("secret"))
+	if err != nil || !found.HasScope(models.ScopeReport) || found.HasScope(models.ScopeOverride) {
+		t.Fatalf("Invalid token %v, %v", found, err)
+	}
+	if !found.Active(time.Now()) {
+		t.Fatal("Expected active token")
+	}
+
+	if err = store.RevokeAPIToken("grader", time.Now()); err != nil {
+		t.Fatal("Failed to revoke token:", err)
+	}
+	if err = store.RevokeAPIToken("grader", time.Now()); err == nil {
+		t.Fatal("Expected error on second revocation")
+	}
+	found, _ = store.FindAPIToken(models.HashAPIToken("secret"))
+	if found.Active(time.Now()) {
+		t.Fatal("Expected revoked token")
+	}
+}
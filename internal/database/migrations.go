@@ -0,0 +1,355 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is the versioned schema change, statements of each step run in a single transaction.
+// Statements are idempotent, so databases created by gorm AutoMigrate are migrated as well.
+type Migration struct {
+	Version int
+	Name    string
+	Up      []string
+	Down    []string
+}
+
+// SchemaMigration is the row of the schema version table, one per applied migration.
+type SchemaMigration struct {
+	Version   int `gorm:"primaryKey"`
+	Name      string
+	AppliedAt time.Time
+}
+
+const createSchemaMigrations = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	applied_at timestamptz NOT NULL
+)`
+
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "initial",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				updated_at timestamptz,
+				deleted_at timestamptz,
+				gitlab_id bigint,
+				gitlab_login text,
+				repository text,
+				first_name text,
+				last_name text,
+				group_name text,
+				telegram_id bigint,
+				has_retake boolean
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_gitlab_id ON users (gitlab_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_gitlab_login ON users (gitlab_login)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_name ON users (first_name, last_name, group_name)`,
+			`CREATE TABLE IF NOT EXISTS pipelines (
+				id bigserial PRIMARY KEY,
+				project text,
+				task text,
+				status text,
+				started_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_pipelines_project ON pipelines (project)`,
+			`CREATE INDEX IF NOT EXISTS idx_pipelines_task ON pipelines (task)`,
+			`CREATE TABLE IF NOT EXISTS sessions (
+				id bigserial PRIMARY KEY,
+				token text,
+				user_id bigint
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_token ON sessions (token)`,
+			`CREATE TABLE IF NOT EXISTS flags (
+				id text PRIMARY KEY,
+				task text,
+				gitlab_login text,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_flags_task ON flags (task)`,
+			`CREATE INDEX IF NOT EXISTS idx_flags_gitlab_login ON flags (gitlab_login)`,
+			`CREATE TABLE IF NOT EXISTS overridden_scores (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				updated_at timestamptz,
+				deleted_at timestamptz,
+				gitlab_login text,
+				task text,
+				score bigint,
+				status text
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_overridden_scores_deleted_at ON overridden_scores (deleted_at)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_overrides ON overridden_scores (gitlab_login, task)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS overridden_scores`,
+			`DROP TABLE IF EXISTS flags`,
+			`DROP TABLE IF EXISTS sessions`,
+			`DROP TABLE IF EXISTS pipelines`,
+			`DROP TABLE IF EXISTS users`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "extensions",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS extensions (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				updated_at timestamptz,
+				deleted_at timestamptz,
+				gitlab_login text,
+				task text,
+				deadline timestamptz,
+				reason text
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_extensions_deleted_at ON extensions (deleted_at)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_extensions ON extensions (gitlab_login, task)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS extensions`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "retake policies",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS retake_policy text NOT NULL DEFAULT ''`,
+			`DO $$ BEGIN
+				IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'users' AND column_name = 'has_retake') THEN
+					UPDATE users SET retake_policy = 'default' WHERE has_retake AND retake_policy = '';
+					ALTER TABLE users DROP COLUMN has_retake;
+				END IF;
+			END $$`,
+		},
+		Down: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS has_retake boolean`,
+			`UPDATE users SET has_retake = retake_policy <> ''`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS retake_policy`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "pipeline partial credit",
+		Up: []string{
+			`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS fraction numeric`,
+			`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS tests_passed bigint NOT NULL DEFAULT 0`,
+			`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS tests_total bigint NOT NULL DEFAULT 0`,
+		},
+		Down: []string{
+			`ALTER TABLE pipelines DROP COLUMN IF EXISTS tests_total`,
+			`ALTER TABLE pipelines DROP COLUMN IF EXISTS tests_passed`,
+			`ALTER TABLE pipelines DROP COLUMN IF EXISTS fraction`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "override history",
+		Up: []string{
+			`ALTER TABLE flags ADD COLUMN IF NOT EXISTS submitted_at timestamptz`,
+			`CREATE TABLE IF NOT EXISTS override_histories (
+				id bigserial PRIMARY KEY,
+				gitlab_login text,
+				task text,
+				score bigint,
+				status text,
+				removed boolean,
+				created_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_override_histories_gitlab_login ON override_histories (gitlab_login)`,
+			`CREATE INDEX IF NOT EXISTS idx_override_histories_created_at ON override_histories (created_at)`,
+			// History of the overrides created before it was tracked
+			`INSERT INTO override_histories (gitlab_login, task, score, status, removed, created_at)
+				SELECT gitlab_login, task, score, status, false, updated_at FROM overridden_scores
+				WHERE NOT EXISTS (SELECT 1 FROM override_histories)
+				UNION ALL
+				SELECT gitlab_login, task, 0, '', true, deleted_at FROM overridden_scores
+				WHERE deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM override_histories)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS override_histories`,
+			`ALTER TABLE flags DROP COLUMN IF EXISTS submitted_at`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "materialized scores",
+		Up: []string{
+			`ALTER TABLE pipelines ADD COLUMN IF NOT EXISTS updated_at timestamptz`,
+			`CREATE INDEX IF NOT EXISTS idx_pipelines_updated_at ON pipelines (updated_at)`,
+			`CREATE TABLE IF NOT EXISTS materialized_scores (
+				gitlab_login text PRIMARY KEY,
+				group_name text,
+				scores bytea,
+				updated_at timestamptz
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_materialized_scores_group_name ON materialized_scores (group_name)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS materialized_scores`,
+			`ALTER TABLE pipelines DROP COLUMN IF EXISTS updated_at`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "audit log",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS audit_records (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				actor text,
+				action text,
+				target text,
+				old_value text,
+				new_value text
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_records_created_at ON audit_records (created_at)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_records_actor ON audit_records (actor)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_records_action ON audit_records (action)`,
+			`CREATE INDEX IF NOT EXISTS idx_audit_records_target ON audit_records (target)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS audit_records`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "user roles",
+		Up: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS role text NOT NULL DEFAULT ''`,
+		},
+		Down: []string{
+			`ALTER TABLE users DROP COLUMN IF EXISTS role`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "api tokens",
+		Up: []string{
+			`CREATE TABLE IF NOT EXISTS api_tokens (
+				id bigserial PRIMARY KEY,
+				created_at timestamptz,
+				name text,
+				hash text,
+				scopes text,
+				group_name text,
+				expires_at timestamptz,
+				last_used_at timestamptz,
+				revoked_at timestamptz
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_name ON api_tokens (name)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_api_tokens_hash ON api_tokens (hash)`,
+		},
+		Down: []string{
+			`DROP TABLE IF EXISTS api_tokens`,
+		},
+	},
+}
+
+// LatestSchemaVersion is the version the code expects, OpenDataBase migrates to it on startup.
+var LatestSchemaVersion = migrations[len(migrations)-1].Version
+
+type MigrateOptions struct {
+	// DryRun prints statements to Output without executing them
+	DryRun bool
+	// Output receives the executed statements, may be nil
+	Output io.Writer
+}
+
+func (o *MigrateOptions) printf(format string, args ...interface{}) {
+	if o.Output != nil {
+		fmt.Fprintf(o.Output, format, args...)
+	}
+}
+
+// SchemaVersion returns the latest applied migration, zero for the empty database.
+func (db *DataBase) SchemaVersion() (int, error) {
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// MigrateTo applies up steps of the newer migrations or down steps of the older ones until the target version.
+func (db *DataBase) MigrateTo(target int, options MigrateOptions) error {
+	if target < 0 || target > LatestSchemaVersion {
+		return fmt.Errorf("unknown schema version %d, latest is %d", target, LatestSchemaVersion)
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	if err = db.execMigration(options, createSchemaMigrations); err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current || migration.Version > target {
+			continue
+		}
+		err = db.applyMigration(options, migration, migration.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		migration := migrations[i]
+		if migration.Version > current || migration.Version <= target {
+			continue
+		}
+		err = db.applyMigration(options, migration, migration.Down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *DataBase) execMigration(options MigrateOptions, statement string) error {
+	options.printf("%s;\n", statement)
+	if options.DryRun {
+		return nil
+	}
+	return db.Exec(statement).Error
+}
+
+func (db *DataBase) applyMigration(options MigrateOptions, migration Migration, statements []string, record func(tx *gorm.DB) error) error {
+	options.printf("-- %d: %s\n", migration.Version, migration.Name)
+	if options.DryRun {
+		for _, statement := range statements {
+			options.printf("%s;\n", statement)
+		}
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			options.printf("%s;\n", statement)
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
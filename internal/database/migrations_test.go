@@ -0,0 +1,17 @@
+package database
+
+import "testing"
+
+func TestMigrationsOrder(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Fatalf("Migration %q has version %d, expected %d", migration.Name, migration.Version, i+1)
+		}
+		if len(migration.Up) == 0 || len(migration.Down) == 0 {
+			t.Fatalf("Migration %d has no up or down steps", migration.Version)
+		}
+	}
+	if LatestSchemaVersion != len(migrations) {
+		t.Fatalf("Invalid latest schema version %d", LatestSchemaVersion)
+	}
+}
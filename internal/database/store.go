@@ -0,0 +1,92 @@
+package database
+
+import (
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+// Store is implemented by DataBase backed by Postgres and by MemoryStore used in tests and small deployments.
+type Store interface {
+	AddUser(user *models.User) (*models.User, error)
+	FindUserByID(id uint) (*models.User, error)
+	FindUserByGitlabLogin(login string) (*models.User, error)
+	FindUserByGitlabID(id int) (*models.User, error)
+	// FindUserByTelegramID returns nil user if nobody has the given telegram id
+	FindUserByTelegramID(id int64) (*models.User, error)
+	ListUsersWithoutRepos() ([]*models.User, error)
+	ListGroupUsers(groupName string) ([]*models.User, error)
+	SetUserGitlabAccount(uid uint, user *models.GitlabUser) error
+	SetUserRepository(user *models.User) error
+	SetUserTelegramID(user *models.User) error
+	// SetUserGroupName writes the audit record in the same transaction, nil record is skipped
+	SetUserGroupName(user *models.User, audit *models.AuditRecord) error
+	SetUserRetakePolicy(user *models.User) error
+	SetUserRole(user *models.User) error
+	// SearchUsers finds users by a part of the name or the gitlab login
+	SearchUsers(query string) ([]*models.User, error)
+
+	AddPipeline(pipeline *models.Pipeline) error
+	SetPipelineCredit(pipeline *models.Pipeline) error
+	ListProjectPipelines(project string) ([]models.Pipeline, error)
+	ListAllPipelines() ([]models.Pipeline, error)
+
+	CreateSession(user uint) (*models.Session, error)
+	FindSession(token string) (*models.Session, error)
+	FindUserBySession(token string) (*models.User, *models.Session, error)
+
+	CreateFlag(task string) (*models.Flag, error)
+	SubmitFlag(id, gitlabLogin string) error
+	ListUserFlags(gitlabLogin string) ([]models.Flag, error)
+	ListSubmittedFlags() ([]models.Flag, error)
+
+	ListUserOverrides(login string) ([]models.OverriddenScore, error)
+	ListOverrides() ([]models.OverriddenScore, error)
+	// AddOverride writes the audit record in the same transaction, nil record is skipped
+	AddOverride(gitlabLogin, task string, score int, status models.PipelineStatus, audit *models.AuditRecord) error
+	RemoveOverride(gitlabLogin, task string) error
+	ListOverridesAt(at time.Time) ([]models.OverriddenScore, error)
+
+	ListUserExtensions(login string) ([]models.Extension, error)
+	ListExtensions() ([]models.Extension, error)
+	AddExtension(gitlabLogin, task string, deadline time.Time, reason string) error
+	RemoveExtension(gitlabLogin, task string) error
+
+	SaveMaterializedScores(scores *models.MaterializedScores) error
+	FindMaterializedScores(login string) (*models.MaterializedScores, error)
+	ListMaterializedScores(groupName string) ([]models.MaterializedScores, error)
+	ListProjectsChangedSince(since time.Time) ([]string, error)
+	ListLoginsChangedSince(since time.Time) ([]string, error)
+
+	AddAuditRecord(record *models.AuditRecord) error
+	ListAuditRecords(filter AuditFilter) ([]models.AuditRecord, error)
+
+	CreateAPIToken(token *models.APIToken) error
+	// FindAPIToken finds the token by hash including revoked and expired ones
+	FindAPIToken(hash string) (*models.APIToken, error)
+	ListAPITokens() ([]models.APIToken, error)
+	RevokeAPIToken(name string, at time.Time) error
+	TouchAPIToken(id uint, at time.Time) error
+}
+
+// AuditFilter selects audit records, zero fields match everything.
+type AuditFilter struct {
+	Actor  string
+	Action string
+	Target string
+	Since  time.Time
+	// Limit keeps only the latest records
+	Limit int
+}
+
+func (f *AuditFilter) matches(record *models.AuditRecord) bool {
+	return (f.Actor == "" || record.Actor == f.Actor) &&
+		(f.Action == "" || record.Action == f.Action) &&
+		(f.Target == "" || record.Target == f.Target) &&
+		(f.Since.IsZero() || !record.CreatedAt.Before(f.Since))
+}
+
+var (
+	_ Store = (*DataBase)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -0,0 +1,58 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// testStores returns the stores which must behave the same, postgres is tested only if NOTMANYTASK_TEST_DSN is set.
+func testStores(t *testing.T) map[string]Store {
+	stores := map[string]Store{"memory": NewMemoryStore()}
+	if dsn := os.Getenv("NOTMANYTASK_TEST_DSN"); dsn != "" {
+		db, err := OpenDataBase(zap.NewNop(), dsn)
+		if err != nil {
+			t.Fatal("Failed to open test database:", err)
+		}
+		stores["postgres"] = db
+	}
+	return stores
+}
+
+func TestStoreExtensionChanges(t *testing.T) {
+	for name, store := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			login := fmt.Sprintf("ivanov-%d", time.Now().UnixNano())
+			deadline := time.Date(2022, time.March, 1, 20, 0, 0, 0, time.UTC)
+
+			if err := store.AddExtension(login, "sum", deadline, "illness"); err != nil {
+				t.Fatal("Failed to add extension:", err)
+			}
+			added := time.Now()
+			time.Sleep(10 * time.Millisecond)
+
+			if logins, _ := store.ListLoginsChangedSince(added); contains(logins, login) {
+				t.Fatalf("Unchanged extension is reported: %v", logins)
+			}
+
+			if err := store.AddExtension(login, "sum", deadline.Add(24*time.Hour), "illness"); err != nil {
+				t.Fatal("Failed to update extension:", err)
+			}
+			if logins, _ := store.ListLoginsChangedSince(added); !contains(logins, login) {
+				t.Fatalf("Changed extension is not reported: %v", logins)
+			}
+		})
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package deadlines
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// AggregationSum counts every task of the scoring group
+	AggregationSum = "sum"
+	// AggregationBest counts only Count best task scores
+	AggregationBest = "best"
+	// AggregationDropLowest drops Count lowest task scores
+	AggregationDropLowest = "dropLowest"
+	// AggregationCap counts every task, but the total never exceeds the max score
+	AggregationCap = "cap"
+)
+
+const (
+	// SelectionFirstSuccess picks the pipeline with the best status, then with the most partial credit, then the earliest one
+	SelectionFirstSuccess = "firstSuccess"
+	// SelectionLatest picks the latest finished pipeline whatever its status is, running pipelines are ignored
+	SelectionLatest = "latest"
+	// SelectionBest picks the pipeline with the highest score after late penalties
+	SelectionBest = "best"
+)
+
+func (g *ScoringGroup) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type G ScoringGroup
+	if err := unmarshal((*G)(g)); err != nil {
+		return err
+	}
+
+	switch g.Aggregation {
+	case "", AggregationSum, AggregationBest, AggregationDropLowest, AggregationCap:
+	default:
+		return fmt.Errorf("unknown aggregation %s of scoring group %s", g.Aggregation, g.Name)
+	}
+	switch g.Selection {
+	case "", SelectionFirstSuccess, SelectionLatest, SelectionBest:
+	default:
+		return fmt.Errorf("unknown selection %s of scoring group %s", g.Selection, g.Name)
+	}
+	if g.Count < 0 {
+		return fmt.Errorf("negative count %d of scoring group %s", g.Count, g.Name)
+	}
+	return nil
+}
+
+// selectCounted reports which of the task scores count towards the group total.
+func (g *ScoringGroup) selectCounted(scores []int) []bool {
+	counted := make([]bool, len(scores))
+
+	// Indices of the scores from the highest to the lowest one, earlier tasks win ties
+	order := make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+
+	limit := len(scores)
+	switch g.Aggregation {
+	case AggregationBest:
+		if g.Count < limit {
+			limit = g.Count
+		}
+	case AggregationDropLowest:
+		limit -= g.Count
+	}
+
+	for i := 0; i < limit; i++ {
+		counted[order[i]] = true
+	}
+	return counted
+}
+
+// Aggregate computes the group total from the task scores.
+// Returns the total and whether each of the task scores was counted.
+func (g *ScoringGroup) Aggregate(scores []int) (total int, counted []bool) {
+	counted = g.selectCounted(scores)
+	for i, score := range scores {
+		if counted[i] {
+			total += score
+		}
+	}
+
+	if (g.Clamp || g.Aggregation == AggregationCap) && g.MaxScore > 0 && total > g.MaxScore {
+		total = g.MaxScore
+	}
+	return total, counted
+}
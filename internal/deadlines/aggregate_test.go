@@ -0,0 +1,133 @@
+package deadlines
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const aggregationYaml = `
+scoring:
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: pool
+    weight: 0.5
+    policy: hard
+    aggregation: best
+    count: 2
+  - name: weekly
+    weight: 0.5
+    policy: hard
+    aggregation: dropLowest
+    count: 1
+  defaultGroup: weekly
+
+assignments:
+- title:    pool
+  group:    pool
+  start:    10-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: a
+      score: 100
+    - task: b
+      score: 300
+    - task: c
+      score: 200
+- title:    weekly
+  start:    10-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: d
+      score: 50
+    - task: e
+      score: 100
+`
+
+func checkAggregate(t *testing.T, group *ScoringGroup, scores []int, expectedTotal int, expectedCounted []bool) {
+	t.Helper()
+	total, counted := group.Aggregate(scores)
+	if total != expectedTotal {
+		t.Fatalf("Invalid total of %v: %d, expected: %d", scores, total, expectedTotal)
+	}
+	if diff := cmp.Diff(expectedCounted, counted); diff != "" {
+		t.Fatalf("Unexpected counted tasks of %v (-want +got):\n%s", scores, diff)
+	}
+}
+
+func TestAggregation(t *testing.T) {
+	sum := &ScoringGroup{}
+	checkAggregate(t, sum, []int{1, 2, 3}, 6, []bool{true, true, true})
+	checkAggregate(t, sum, []int{}, 0, []bool{})
+
+	best := &ScoringGroup{Aggregation: AggregationBest, Count: 2}
+	checkAggregate(t, best, []int{10, 30, 20}, 50, []bool{false, true, true})
+	checkAggregate(t, best, []int{10, 10, 10}, 20, []bool{true, true, false})
+	checkAggregate(t, best, []int{10}, 10, []bool{true})
+
+	drop := &ScoringGroup{Aggregation: AggregationDropLowest, Count: 1}
+	checkAggregate(t, drop, []int{10, 30, 20}, 50, []bool{false, true, true})
+	checkAggregate(t, drop, []int{}, 0, []bool{})
+
+	capped := &ScoringGroup{Aggregation: AggregationCap, MaxScore: 40}
+	checkAggregate(t, capped, []int{10, 30, 20}, 40, []bool{true, true, true})
+
+	extra := &ScoringGroup{MaxScore: 40}
+	checkAggregate(t, extra, []int{10, 30, 20}, 60, []bool{true, true, true})
+
+	clamped := &ScoringGroup{Aggregation: AggregationBest, Count: 2, MaxScore: 40, Clamp: true}
+	checkAggregate(t, clamped, []int{10, 30, 20}, 40, []bool{false, true, true})
+}
+
+func TestAggregationMaxScore(t *testing.T) {
+	deadlines, err := parseV2([]byte(aggregationYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+
+	if maxScore := deadlines.GetScoringGroup(&deadlines.Assignments[0]).MaxScore; maxScore != 500 {
+		t.Fatalf("Invalid best-of max score: %d", maxScore)
+	}
+	if maxScore := deadlines.GetScoringGroup(&deadlines.Assignments[1]).MaxScore; maxScore != 100 {
+		t.Fatalf("Invalid drop-lowest max score: %d", maxScore)
+	}
+}
+
+func TestUnknownAggregation(t *testing.T) {
+	_, err := parseV2([]byte(`
+scoring:
+  groups:
+  - name: weekly
+    aggregation: median
+`))
+	if err == nil {
+		t.Fatal("Expected error for unknown aggregation")
+	}
+}
+
+func TestSelection(t *testing.T) {
+	deadlines, err := parseV2([]byte(`
+scoring:
+  groups:
+  - name: weekly
+    selection: latest
+`))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+	if selection := deadlines.Scoring.Groups[0].Selection; selection != SelectionLatest {
+		t.Fatalf("Invalid selection: %s", selection)
+	}
+
+	_, err = parseV2([]byte(`
+scoring:
+  groups:
+  - name: weekly
+    selection: random
+`))
+	if err == nil {
+		t.Fatal("Expected error for unknown selection")
+	}
+}
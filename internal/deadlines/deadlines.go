@@ -10,13 +10,19 @@ type Date struct {
 	time.Time
 }
 
-// FIXME(BigRedEye): Do not hardcode Moscow
-
 const (
+	// defaultTimeZone is used if neither deadlines nor group config specify time zone
 	defaultTimeZone = "Europe/Moscow"
 	dateFormat      = "02-01-2006 15:04"
+	zoneFormat      = "MST"
 )
 
+// Dates with explicit offsets are accepted in these formats
+var isoDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+}
+
 var defaultLoc *time.Location
 var defaultLocOnce sync.Once
 
@@ -33,11 +39,21 @@ func getDefaultLocation() *time.Location {
 }
 
 func (t *Date) String() string {
-	return t.Format(dateFormat)
+	return t.Format(dateFormat + " " + zoneFormat)
 }
 
+// UnmarshalText parses either the date in the default time zone or ISO-8601 timestamp with explicit offset.
+// Dates without offsets are moved to the deadlines time zone after parsing, see Deadlines.setLocation.
 func (t *Date) UnmarshalText(buf []byte) error {
-	tt, err := time.ParseInLocation(dateFormat, strings.TrimSpace(string(buf)), getDefaultLocation())
+	text := strings.TrimSpace(string(buf))
+	for _, format := range isoDateFormats {
+		if tt, err := time.Parse(format, text); err == nil {
+			t.Time = tt
+			return nil
+		}
+	}
+
+	tt, err := time.ParseInLocation(dateFormat, text, getDefaultLocation())
 	if err != nil {
 		return err
 	}
@@ -45,8 +61,35 @@ func (t *Date) UnmarshalText(buf []byte) error {
 	return nil
 }
 
+// MarshalText keeps the zone-less format for dates in the default time zone, API clients rely on it.
+// Other dates carry the offset, otherwise they would be read back in the default time zone.
 func (t Date) MarshalText() ([]byte, error) {
-	return []byte(t.String()), nil
+	if t.hasDefaultLocation() {
+		return []byte(t.Format(dateFormat)), nil
+	}
+	return []byte(t.Format(time.RFC3339)), nil
+}
+
+// ParseDate parses the date like UnmarshalText, dates without explicit offset are placed in the given location.
+func ParseDate(text string, loc *time.Location) (Date, error) {
+	date := Date{}
+	if err := date.UnmarshalText([]byte(text)); err != nil {
+		return date, err
+	}
+	date.setLocation(loc)
+	return date, nil
+}
+
+// hasDefaultLocation reports whether the date was parsed without explicit offset
+func (t *Date) hasDefaultLocation() bool {
+	return t.Location() == getDefaultLocation()
+}
+
+func (t *Date) setLocation(loc *time.Location) {
+	if t == nil || !t.hasDefaultLocation() {
+		return
+	}
+	t.Time = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
 }
 
 func (t *Date) UnmarshalJSON(buf []byte) error {
@@ -69,6 +112,13 @@ type Task struct {
 	Task    string
 	Score   int
 	Crashme bool
+	// Required tasks must be solved to pass the course regardless of the total score
+	Required bool `yaml:",omitempty"`
+
+	// Optional per-task overrides of the task group settings
+	Deadline *Date  `yaml:",omitempty"`
+	Group    string `yaml:",omitempty"`
+	Policy   string `yaml:",omitempty"`
 }
 
 type TaskGroup struct {
@@ -84,9 +134,45 @@ type TaskGroup struct {
 type Deadlines struct {
 	Assignments []TaskGroup
 	Scoring     Scoring
+	Timezone    string `yaml:"timezone,omitempty"`
 
 	policies map[string]ScoringPolicy `yaml:"-"`
 	groups   map[string]*ScoringGroup `yaml:"-"`
+	location *time.Location           `yaml:"-"`
+}
+
+// Location returns the time zone of the deadlines.
+func (d *Deadlines) Location() *time.Location {
+	if d == nil || d.location == nil {
+		return getDefaultLocation()
+	}
+	return d.location
+}
+
+// setTimezone moves all the dates parsed without explicit offset to the given time zone.
+func (d *Deadlines) setTimezone(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return err
+	}
+	if loc.String() == defaultTimeZone {
+		return nil
+	}
+	d.location = loc
+
+	for i := range d.Assignments {
+		group := &d.Assignments[i]
+		group.Start.setLocation(loc)
+		group.Deadline.setLocation(loc)
+		for j := range group.Tasks {
+			group.Tasks[j].Deadline.setLocation(loc)
+		}
+	}
+	d.Scoring.FinalDeadline.setLocation(loc)
+	for i := range d.Scoring.RetakePolicies {
+		d.Scoring.RetakePolicies[i].Deadline.setLocation(loc)
+	}
+	return nil
 }
 
 func (d *Deadlines) buildScoringGroups() error {
@@ -101,20 +187,22 @@ func (d *Deadlines) buildScoringGroups() error {
 		d.groups[group.Name] = group
 	}
 
-	maxScores := make(map[string]int)
+	// Retake-only task groups do not count towards max scores: regular students can not solve them
+	taskScores := make(map[string][]int)
 	for i := range d.Assignments {
-		group := d.GetScoringGroup(&d.Assignments[i])
-		if group != nil && group.MaxScore == 0 {
-			totalScore := 0
-			for j := range d.Assignments[i].Tasks {
-				totalScore += d.Assignments[i].Tasks[j].Score
+		if d.GetRetakeGroupPolicy(&d.Assignments[i]) != "" {
+			continue
+		}
+		for j := range d.Assignments[i].Tasks {
+			group := d.GetTaskScoringGroup(&d.Assignments[i], &d.Assignments[i].Tasks[j])
+			if group != nil && group.MaxScore == 0 {
+				taskScores[group.Name] = append(taskScores[group.Name], d.Assignments[i].Tasks[j].Score)
 			}
-			maxScores[group.Name] += totalScore
 		}
 	}
 
-	for k, v := range maxScores {
-		d.groups[k].MaxScore = v
+	for k, v := range taskScores {
+		d.groups[k].MaxScore, _ = d.groups[k].Aggregate(v)
 	}
 
 	return nil
@@ -133,6 +221,19 @@ func (d *Deadlines) GetScoringGroup(group *TaskGroup) *ScoringGroup {
 	return g
 }
 
+// GetTaskScoringGroup resolves scoring group of the task in the order task, task group, default.
+func (d *Deadlines) GetTaskScoringGroup(group *TaskGroup, task *Task) *ScoringGroup {
+	if task.Group == "" {
+		return d.GetScoringGroup(group)
+	}
+
+	g, found := d.groups[task.Group]
+	if !found {
+		return nil
+	}
+	return g
+}
+
 func (d *Deadlines) GetScoringPolicy(group *TaskGroup) ScoringPolicy {
 	scoringGroup := d.GetScoringGroup(group)
 	if scoringGroup == nil {
@@ -146,6 +247,36 @@ func (d *Deadlines) GetScoringPolicy(group *TaskGroup) ScoringPolicy {
 	return policy
 }
 
+// GetTaskScoringPolicy resolves scoring policy of the task.
+// Policy set on the task wins, otherwise the policy of the task scoring group is used.
+func (d *Deadlines) GetTaskScoringPolicy(group *TaskGroup, task *Task) ScoringPolicy {
+	policy, found := d.policies[d.GetTaskScoringPolicyName(group, task)]
+	if !found {
+		return nil
+	}
+	return policy
+}
+
+// GetTaskScoringPolicyName resolves name of the task scoring policy, empty if there is no scoring group.
+func (d *Deadlines) GetTaskScoringPolicyName(group *TaskGroup, task *Task) string {
+	if task.Policy != "" {
+		return task.Policy
+	}
+	scoringGroup := d.GetTaskScoringGroup(group, task)
+	if scoringGroup == nil {
+		return ""
+	}
+	return scoringGroup.Policy
+}
+
+// GetTaskDeadline returns the deadline of the task, falling back to the task group deadline.
+func (d *Deadlines) GetTaskDeadline(group *TaskGroup, task *Task) Date {
+	if task.Deadline != nil {
+		return *task.Deadline
+	}
+	return group.Deadline
+}
+
 func (d *Deadlines) HasTask(name string) bool {
 	for _, assignment := range d.Assignments {
 		for _, task := range assignment.Tasks {
@@ -156,3 +287,12 @@ func (d *Deadlines) HasTask(name string) bool {
 	}
 	return false
 }
+
+func (d *Deadlines) HasTaskGroup(title string) bool {
+	for _, assignment := range d.Assignments {
+		if assignment.Title == title {
+			return true
+		}
+	}
+	return false
+}
@@ -412,3 +412,194 @@ func TestDeadlinesParsingV2(t *testing.T) {
 		}
 	*/
 }
+
+const perTaskOverridesYaml = `
+scoring:
+  policies:
+  - name: soft
+    kind: exp
+    spec:
+      multiplier: 120h
+      threshold: 0.3
+  - name: hard
+    kind: linear
+    spec:
+      after: 5m
+      multiplier: 0
+
+  groups:
+  - name: weekly
+    weight: 4.0
+    policy: soft
+  - name: large
+    weight: 6.0
+    policy: hard
+
+  defaultGroup: weekly
+
+assignments:
+- title:    01-move
+  start:    04-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: 100
+    - task: deque
+      score: 250
+      deadline: 24-09-2021 19:00
+      policy: hard
+    - task: scheme
+      score: 10
+      group: large
+`
+
+func TestPerTaskOverrides(t *testing.T) {
+	deadlines, err := parseV2([]byte(perTaskOverridesYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+
+	group := &deadlines.Assignments[0]
+	dedup, deque, scheme := &group.Tasks[0], &group.Tasks[1], &group.Tasks[2]
+
+	if deadline := deadlines.GetTaskDeadline(group, dedup); !deadline.Equal(group.Deadline.Time) {
+		t.Fatalf("Invalid dedup deadline: %s", deadline.String())
+	}
+	expectedDeadline := time.Date(2021, 9, 24, 19, 0, 0, 0, getDefaultLocation())
+	if deadline := deadlines.GetTaskDeadline(group, deque); !deadline.Equal(expectedDeadline) {
+		t.Fatalf("Invalid deque deadline: %s", deadline.String())
+	}
+
+	if policy := deadlines.GetTaskScoringPolicy(group, dedup); policy != deadlines.policies["soft"] {
+		t.Fatalf("Invalid dedup policy: %+v", policy)
+	}
+	if policy := deadlines.GetTaskScoringPolicy(group, deque); policy != deadlines.policies["hard"] {
+		t.Fatalf("Invalid deque policy: %+v", policy)
+	}
+	if policy := deadlines.GetTaskScoringPolicy(group, scheme); policy != deadlines.policies["hard"] {
+		t.Fatalf("Invalid scheme policy: %+v", policy)
+	}
+
+	if scoringGroup := deadlines.GetTaskScoringGroup(group, deque); scoringGroup.Name != "weekly" || scoringGroup.MaxScore != 350 {
+		t.Fatalf("Invalid deque scoring group: %+v", scoringGroup)
+	}
+	if scoringGroup := deadlines.GetTaskScoringGroup(group, scheme); scoringGroup.Name != "large" || scoringGroup.MaxScore != 10 {
+		t.Fatalf("Invalid scheme scoring group: %+v", scoringGroup)
+	}
+}
+
+const timezoneYaml = `
+timezone: Asia/Yekaterinburg
+
+assignments:
+- title:    01-move
+  start:    04-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: 100
+    - task: deque
+      score: 250
+      deadline: 2021-09-24T19:00:00+03:00
+`
+
+func TestTimezone(t *testing.T) {
+	deadlines, err := parseV2([]byte(timezoneYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+
+	loc, err := time.LoadLocation("Asia/Yekaterinburg")
+	if err != nil {
+		t.Fatal("Failed to load location:", err)
+	}
+
+	group := &deadlines.Assignments[0]
+	expected := time.Date(2021, 9, 17, 19, 0, 0, 0, loc)
+	if !group.Deadline.Equal(expected) || group.Deadline.Location().String() != loc.String() {
+		t.Fatalf("Invalid deadline: %s, expected: %s", group.Deadline.String(), expected)
+	}
+	if str := group.Deadline.String(); str != "17-09-2021 19:00 +05" {
+		t.Fatalf("Invalid deadline string: %s", str)
+	}
+
+	expected = time.Date(2021, 9, 24, 16, 0, 0, 0, time.UTC)
+	if deadline := group.Tasks[1].Deadline; !deadline.Equal(expected) {
+		t.Fatalf("Invalid task deadline: %s, expected: %s", deadline.String(), expected)
+	}
+}
+
+func TestDateMarshalRoundTrip(t *testing.T) {
+	date := Date{time.Date(2021, 9, 17, 19, 0, 0, 0, getDefaultLocation())}
+	text, err := date.MarshalText()
+	if err != nil {
+		t.Fatal("Failed to marshal date:", err)
+	}
+	if string(text) != "17-09-2021 19:00" {
+		t.Fatalf("Invalid marshaled date: %s", text)
+	}
+
+	parsed := Date{}
+	if err = parsed.UnmarshalText(text); err != nil {
+		t.Fatal("Failed to unmarshal date:", err)
+	}
+	if !parsed.Equal(date.Time) {
+		t.Fatalf("Invalid date: %s, expected: %s", parsed.String(), date.String())
+	}
+}
+
+func TestParseDateInLocation(t *testing.T) {
+	deadlines, err := parseV2([]byte(timezoneYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+	loc := deadlines.Location()
+	if loc.String() != "Asia/Yekaterinburg" {
+		t.Fatalf("Invalid deadlines location: %s", loc)
+	}
+
+	date, err := ParseDate("17-09-2021 19:00", loc)
+	if err != nil {
+		t.Fatal("Failed to parse date:", err)
+	}
+	if expected := time.Date(2021, 9, 17, 19, 0, 0, 0, loc); !date.Equal(expected) {
+		t.Fatalf("Invalid date: %s, expected: %s", date.String(), expected)
+	}
+
+	date, err = ParseDate("2021-09-17T19:00:00+03:00", loc)
+	if err != nil {
+		t.Fatal("Failed to parse date:", err)
+	}
+	if expected := time.Date(2021, 9, 17, 16, 0, 0, 0, time.UTC); !date.Equal(expected) {
+		t.Fatalf("Explicit offset is ignored: %s, expected: %s", date.String(), expected)
+	}
+
+	if (*Deadlines)(nil).Location() != getDefaultLocation() {
+		t.Fatal("Deadlines without time zone are not in the default location")
+	}
+}
+
+func TestDateMarshalKeepsOffset(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Yekaterinburg")
+	if err != nil {
+		t.Fatal("Failed to load location:", err)
+	}
+
+	for _, date := range []Date{
+		{time.Date(2021, 9, 17, 19, 0, 0, 0, loc)},
+		{time.Date(2021, 9, 17, 19, 0, 0, 0, time.UTC)},
+	} {
+		text, err := date.MarshalText()
+		if err != nil {
+			t.Fatal("Failed to marshal date:", err)
+		}
+
+		parsed := Date{}
+		if err = parsed.UnmarshalText(text); err != nil {
+			t.Fatal("Failed to unmarshal date:", err)
+		}
+		if !parsed.Equal(date.Time) {
+			t.Fatalf("Invalid date %s parsed from %s, expected: %s", parsed.String(), text, date.String())
+		}
+	}
+}
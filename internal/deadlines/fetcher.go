@@ -2,12 +2,12 @@ package deadlines
 
 import (
 	"context"
-	"io"
-	"net/http"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
@@ -19,7 +19,7 @@ func parseV1(body []byte) (*Deadlines, error) {
 	assignments := []TaskGroup{}
 	err := yaml.Unmarshal(body, &assignments)
 	if err != nil {
-		return nil, errors.New("Failed to unmarshal deadlines")
+		return nil, errors.Wrap(err, "Failed to unmarshal deadlines")
 	}
 
 	deadlines := &Deadlines{
@@ -54,7 +54,13 @@ func parseV2(body []byte) (*Deadlines, error) {
 	deadlines := &Deadlines{}
 	err := yaml.Unmarshal(body, &deadlines)
 	if err != nil {
-		return nil, errors.New("Failed to unmarshal deadlines")
+		return nil, errors.Wrap(err, "Failed to unmarshal deadlines")
+	}
+
+	if deadlines.Timezone != "" {
+		if err = deadlines.setTimezone(deadlines.Timezone); err != nil {
+			return nil, errors.Wrap(err, "Failed to load deadlines time zone")
+		}
 	}
 
 	err = deadlines.buildScoringGroups()
@@ -65,21 +71,31 @@ func parseV2(body []byte) (*Deadlines, error) {
 	return deadlines, nil
 }
 
-func fetch(url, format string) (*Deadlines, error) {
-	resp, err := http.Get(url)
+func fetch(ctx context.Context, group *config.GroupConfig, src source) (*Deadlines, error) {
+	body, err := src.read(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "Failed to fetch deadlines")
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("failed to fetch deadlines: %s", resp.Status)
+	deadlines, err := parse(body, group.DeadlinesFormat)
+	if err != nil {
+		src.invalidate()
+		return nil, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, errors.Wrap(err, "Failed to read response")
+	// Time zone from the deadlines file takes precedence over the group config
+	if deadlines.Timezone == "" && group.Timezone != "" {
+		if err = deadlines.setTimezone(group.Timezone); err != nil {
+			src.invalidate()
+			return nil, errors.Wrap(err, "Failed to load group time zone")
+		}
+		deadlines.Timezone = group.Timezone
 	}
 
+	return deadlines, nil
+}
+
+func parse(body []byte, format string) (*Deadlines, error) {
 	switch format {
 	case "v1":
 		return parseV1(body)
@@ -90,26 +106,44 @@ func fetch(url, format string) (*Deadlines, error) {
 	}
 }
 
+type GroupStatus struct {
+	Group       string
+	LastAttempt time.Time
+	// LastSuccess is zero if deadlines were never fetched successfully
+	LastSuccess time.Time
+	// LastError is empty if the last attempt succeeded
+	LastError string `json:",omitempty"`
+}
+
+type groupState struct {
+	deadlines *Deadlines
+	source    source
+	status    GroupStatus
+}
+
 type Fetcher struct {
 	current atomic.Value
 
+	// states are modified only by reload, mutex guards concurrent status readers
+	// and is not held during fetches
+	states   map[string]*groupState
+	statesMu sync.Mutex
+
 	config *config.Config
 	logger *zap.Logger
 }
 
 func NewFetcher(conf *config.Config, logger *zap.Logger) (*Fetcher, error) {
 	fetcher := &Fetcher{
+		states: make(map[string]*groupState),
 		config: conf,
 		logger: logger,
 	}
 
+	// Groups that failed to load are retried in Run
 	err := fetcher.reload()
 	if err != nil {
-		return nil, err
-	}
-
-	if fetcher.current.Load() == nil {
-		panic("No deadlines found after reload")
+		logger.Error("Failed to load some deadlines on startup", zap.Error(err))
 	}
 
 	return fetcher, nil
@@ -118,43 +152,168 @@ func NewFetcher(conf *config.Config, logger *zap.Logger) (*Fetcher, error) {
 func (f *Fetcher) Run(ctx context.Context) {
 	tick := time.NewTicker(f.config.PullIntervals.Deadlines)
 
+	watcher, err := f.watchFiles()
+	if err != nil {
+		f.logger.Error("Failed to watch deadlines files, falling back to polling", zap.Error(err))
+	}
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	if watcher != nil {
+		defer watcher.Close()
+		events, errs = watcher.Events, watcher.Errors
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-tick.C:
 			_ = f.reload()
+		case event := <-events:
+			f.logger.Debug("Deadlines file changed", zap.String("file", event.Name), zap.Stringer("op", event.Op))
+			_ = f.reload()
+		case err := <-errs:
+			f.logger.Error("Deadlines watcher failed", zap.Error(err))
+		}
+	}
+}
+
+// watchFiles watches local deadlines files for instant reload.
+// Returns nil watcher if there is nothing to watch.
+func (f *Fetcher) watchFiles() (*fsnotify.Watcher, error) {
+	paths := make(map[string]bool)
+	for i := range f.config.Groups {
+		src, _ := f.getSource(&f.config.Groups[i])
+		if src, ok := src.(*fileSource); ok {
+			paths[src.watchPath()] = true
+		}
+	}
+	if len(paths) == 0 {
+		return nil, nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for path := range paths {
+		if err = watcher.Add(path); err != nil {
+			_ = watcher.Close()
+			return nil, errors.Wrapf(err, "Failed to watch %s", path)
+		}
+	}
+	return watcher, nil
+}
+
+func (f *Fetcher) getSource(group *config.GroupConfig) (source, error) {
+	f.statesMu.Lock()
+	defer f.statesMu.Unlock()
+	state := f.getStateLocked(group.Name)
+	if state.source == nil {
+		src, err := newSource(group.DeadlinesURL)
+		if err != nil {
+			return nil, err
 		}
+		state.source = src
+	}
+	return state.source, nil
+}
+
+func (f *Fetcher) getStateLocked(group string) *groupState {
+	state, found := f.states[group]
+	if !found {
+		state = &groupState{status: GroupStatus{Group: group}}
+		f.states[group] = state
+	}
+	return state
+}
+
+// fetchGroup bounds the fetch by the pull interval, so a hanging source does not block other groups.
+func (f *Fetcher) fetchGroup(group *config.GroupConfig, src source) (*Deadlines, error) {
+	ctx := context.Background()
+	if timeout := f.config.PullIntervals.Deadlines; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
+	return fetch(ctx, group, src)
 }
 
 type deadlinesMap = map[string]*Deadlines
 
+// reload fetches deadlines of each group independently.
+// Group that failed to reload keeps serving its last successfully fetched deadlines.
 func (f *Fetcher) reload() error {
 	f.logger.Debug("Start deadlines fetcher iteration")
 	defer f.logger.Debug("Finish deadlines fetcher iteration")
 
+	var lastErr error
+	updated := false
 	groupDeadlines := make(deadlinesMap)
-	for _, group := range f.config.Groups {
-		deadlines, err := fetch(group.DeadlinesURL, group.DeadlinesFormat)
-		if err != nil {
-			f.logger.Error("Failed to reload deadlines", zap.Error(err))
-			return errors.Wrap(err, "Failed to reload deadlines")
+	for i := range f.config.Groups {
+		group := &f.config.Groups[i]
+		log := f.logger.With(zap.String("group", group.Name))
+
+		f.statesMu.Lock()
+		state := f.getStateLocked(group.Name)
+		f.statesMu.Unlock()
+
+		attempt := time.Now()
+		var deadlines *Deadlines
+		src, err := f.getSource(group)
+		if err == nil {
+			deadlines, err = f.fetchGroup(group, src)
+		}
+
+		f.statesMu.Lock()
+		state.status.LastAttempt = attempt
+		switch {
+		case errors.Is(err, errNotModified):
+			log.Debug("Deadlines were not modified")
+			state.status.LastSuccess = state.status.LastAttempt
+			state.status.LastError = ""
+		case err != nil:
+			log.Error("Failed to reload deadlines", zap.Error(err), zap.Time("last_success", state.status.LastSuccess))
+			state.status.LastError = err.Error()
+			fetchErrors.WithLabelValues(group.Name).Inc()
+			lastErr = errors.Wrapf(err, "Failed to reload deadlines for group %s", group.Name)
+		default:
+			log.Debug("Successfully fetched deadlines", zap.Int("num_task_groups", len(deadlines.Assignments)))
+			state.status.LastSuccess = state.status.LastAttempt
+			state.status.LastError = ""
+			// Unchanged deadlines keep their pointer, consumers detect changes by pointer comparison
+			if !reflect.DeepEqual(state.deadlines, deadlines) {
+				updated = true
+				state.deadlines = deadlines
+			}
+		}
+		f.statesMu.Unlock()
+
+		if state.deadlines != nil {
+			groupDeadlines[group.Name] = state.deadlines
 		}
-		groupDeadlines[group.Name] = deadlines
-		f.logger.Debug("Successfully fetched deadlines",
-			zap.Int("num_task_groups", len(deadlines.Assignments)),
-			zap.String("group", group.Name),
-		)
 	}
-	f.logger.Debug("Successfully fetched all deadlines")
 
-	prev := f.current.Swap(groupDeadlines)
-	if !reflect.DeepEqual(prev, groupDeadlines) {
+	f.current.Store(groupDeadlines)
+	if updated {
 		f.logger.Info("Updated deadlines")
 	}
 
-	return nil
+	return lastErr
+}
+
+// GroupStatuses returns fetch status of each configured group
+func (f *Fetcher) GroupStatuses() []GroupStatus {
+	f.statesMu.Lock()
+	defer f.statesMu.Unlock()
+
+	statuses := make([]GroupStatus, 0, len(f.config.Groups))
+	for _, group := range f.config.Groups {
+		if state, found := f.states[group.Name]; found {
+			statuses = append(statuses, state.status)
+		}
+	}
+	return statuses
 }
 
 func (f *Fetcher) GroupDeadlines(group string) *Deadlines {
@@ -179,3 +338,17 @@ func (f *Fetcher) AnyGroupHasTask(task string) bool {
 	}
 	return false
 }
+
+func (f *Fetcher) AnyGroupHasTaskGroup(title string) bool {
+	cur := f.current.Load()
+	if cur == nil {
+		return false
+	}
+	groupDeadlines := cur.(deadlinesMap)
+	for _, deadlines := range groupDeadlines {
+		if deadlines.HasTaskGroup(title) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,161 @@
+package deadlines
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+)
+
+const fetcherDeadlinesYaml = `
+- title:    Intro
+  start:    01-01-2021 00:00
+  deadline: 28-02-2021 23:59
+  tasks:
+    - task: intro/aplusb
+      score: 100
+`
+
+func TestFetcherKeepsLastGoodDeadlines(t *testing.T) {
+	var broken atomic.Bool
+	var requests, notModified atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/good.yml":
+			if broken.Load() {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(fetcherDeadlinesYaml))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	conf := &config.Config{
+		Groups: config.GroupsConfig{{
+			Name:         "good",
+			DeadlinesURL: server.URL + "/good.yml",
+		}, {
+			Name:         "missing",
+			DeadlinesURL: server.URL + "/missing.yml",
+		}},
+	}
+
+	fetcher, err := NewFetcher(conf, zap.NewNop())
+	if err != nil {
+		t.Fatal("Failed to create fetcher:", err)
+	}
+	if fetcher.GroupDeadlines("good") == nil {
+		t.Fatal("Deadlines for the good group were not loaded")
+	}
+	if fetcher.GroupDeadlines("missing") != nil {
+		t.Fatal("Unexpected deadlines for the missing group")
+	}
+
+	if err = fetcher.reload(); err == nil {
+		t.Fatal("Expected error for the missing group")
+	}
+	if notModified.Load() != 1 {
+		t.Fatalf("Expected conditional request, got %d not modified responses", notModified.Load())
+	}
+
+	broken.Store(true)
+	_ = fetcher.reload()
+	if fetcher.GroupDeadlines("good") == nil {
+		t.Fatal("Last good deadlines were dropped")
+	}
+
+	statuses := fetcher.GroupStatuses()
+	if len(statuses) != 2 {
+		t.Fatalf("Invalid number of group statuses: %d", len(statuses))
+	}
+	if statuses[0].LastError == "" || statuses[0].LastSuccess.IsZero() {
+		t.Fatalf("Invalid good group status: %+v", statuses[0])
+	}
+	if statuses[1].LastError == "" || !statuses[1].LastSuccess.IsZero() {
+		t.Fatalf("Invalid missing group status: %+v", statuses[1])
+	}
+}
+
+func TestFetcherKeepsUnchangedDeadlines(t *testing.T) {
+	content := fetcherDeadlinesYaml
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// No ETag, every fetch downloads the whole file
+		_, _ = w.Write([]byte(content))
+	}))
+	defer server.Close()
+
+	conf := &config.Config{
+		Groups: config.GroupsConfig{{Name: "hse", DeadlinesURL: server.URL + "/deadlines.yml", Timezone: "Europe/Moscow"}},
+	}
+	fetcher, err := NewFetcher(conf, zap.NewNop())
+	if err != nil {
+		t.Fatal("Failed to create fetcher:", err)
+	}
+	first := fetcher.GroupDeadlines("hse")
+
+	if err = fetcher.reload(); err != nil {
+		t.Fatal("Failed to reload deadlines:", err)
+	}
+	if fetcher.GroupDeadlines("hse") != first {
+		t.Fatal("Unchanged deadlines were replaced")
+	}
+
+	content += "      required: true\n"
+	if err = fetcher.reload(); err != nil {
+		t.Fatal("Failed to reload deadlines:", err)
+	}
+	if fetcher.GroupDeadlines("hse") == first {
+		t.Fatal("Changed deadlines were not replaced")
+	}
+}
+
+func TestFetcherTimesOutHangingSource(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	conf := &config.Config{
+		Groups: config.GroupsConfig{{
+			Name:         "hanging",
+			DeadlinesURL: server.URL + "/deadlines.yml",
+		}},
+		PullIntervals: config.PullIntervalsConfig{Deadlines: 50 * time.Millisecond},
+	}
+
+	fetcher, err := NewFetcher(conf, zap.NewNop())
+	if err != nil {
+		t.Fatal("Failed to create fetcher:", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fetcher.reload() }()
+	select {
+	case err = <-done:
+		if err == nil {
+			t.Error("Expected reload of hanging source to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reload of hanging source did not time out")
+	}
+}
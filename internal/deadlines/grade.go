@@ -0,0 +1,141 @@
+package deadlines
+
+import (
+	"fmt"
+	"math"
+)
+
+// Grading converts the weighted final mark to the university grade scale.
+type Grading struct {
+	// Scale multiplies the final mark if there are no thresholds, defaults to 1
+	Scale float64
+	// Rounding is one of round (default), floor, ceil or none
+	Rounding string
+	// Thresholds map final marks to grades, the highest threshold reached wins
+	Thresholds []GradeThreshold
+	// Groups set caps and requirements per scoring group
+	Groups []GradeGroup
+	// FailGrade is the highest grade of students who did not meet some requirement
+	FailGrade float64 `yaml:"failGrade"`
+	// Max is the highest possible grade, zero disables the cap
+	Max float64
+}
+
+type GradeThreshold struct {
+	Mark  float64
+	Grade float64
+}
+
+type GradeGroup struct {
+	Name string
+	// Cap limits the scoring group contribution to the final mark, zero disables the cap
+	Cap float64
+	// MinRatio is the minimal share of the scoring group max score required to pass
+	MinRatio float64 `yaml:"minRatio"`
+}
+
+// GroupMark is the result of a student in one scoring group.
+type GroupMark struct {
+	// Mark is the weighted contribution of the group to the final mark
+	Mark     float64
+	Score    int
+	MaxScore int
+}
+
+const (
+	RoundingRound = "round"
+	RoundingFloor = "floor"
+	RoundingCeil  = "ceil"
+	RoundingNone  = "none"
+)
+
+// Grade rounding should not suffer from float errors like 0.57 * 10 = 5.699999999999999
+const roundingEpsilon = 1e-9
+
+func (g *Grading) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type G Grading
+	if err := unmarshal((*G)(g)); err != nil {
+		return err
+	}
+
+	switch g.Rounding {
+	case "", RoundingRound, RoundingFloor, RoundingCeil, RoundingNone:
+		return nil
+	default:
+		return fmt.Errorf("unknown rounding mode %s", g.Rounding)
+	}
+}
+
+func (g *Grading) round(grade float64) float64 {
+	switch g.Rounding {
+	case RoundingFloor:
+		return math.Floor(grade + roundingEpsilon)
+	case RoundingCeil:
+		return math.Ceil(grade - roundingEpsilon)
+	case RoundingNone:
+		return grade
+	default:
+		return math.Round(grade)
+	}
+}
+
+func (g *Grading) findGroup(name string) *GradeGroup {
+	for i := range g.Groups {
+		if g.Groups[i].Name == name {
+			return &g.Groups[i]
+		}
+	}
+	return nil
+}
+
+// FinalGrade computes the grade from the per scoring group marks.
+// Returns nil if the scoring section has no grading.
+func (s *Scoring) FinalGrade(marks map[string]GroupMark) *float64 {
+	g := s.Grade
+	if g == nil {
+		return nil
+	}
+
+	mark := 0.0
+	for i := range s.Groups {
+		contribution := marks[s.Groups[i].Name].Mark
+		if group := g.findGroup(s.Groups[i].Name); group != nil && group.Cap > 0 {
+			contribution = math.Min(contribution, group.Cap)
+		}
+		mark += contribution
+	}
+
+	var grade float64
+	if len(g.Thresholds) > 0 {
+		reached := math.Inf(-1)
+		for _, threshold := range g.Thresholds {
+			if mark+roundingEpsilon >= threshold.Mark && threshold.Mark > reached {
+				reached = threshold.Mark
+				grade = threshold.Grade
+			}
+		}
+	} else {
+		scale := g.Scale
+		if scale == 0 {
+			scale = 1
+		}
+		grade = mark * scale
+	}
+
+	grade = g.round(grade)
+	if g.Max > 0 {
+		grade = math.Min(grade, g.Max)
+	}
+
+	for _, group := range g.Groups {
+		mark := marks[group.Name]
+		if group.MinRatio <= 0 || mark.MaxScore <= 0 {
+			continue
+		}
+		if float64(mark.Score)+roundingEpsilon < group.MinRatio*float64(mark.MaxScore) {
+			grade = math.Min(grade, g.FailGrade)
+		}
+	}
+
+	return &grade
+}
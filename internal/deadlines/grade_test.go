@@ -0,0 +1,108 @@
+package deadlines
+
+import (
+	"fmt"
+	"testing"
+)
+
+const gradeYaml = `
+scoring:
+  groups:
+  - name: homework
+    weight: 0.7
+    maxScore: 100
+  - name: exam
+    weight: 0.4
+    maxScore: 10
+  grade:
+    scale: 10
+    rounding: %s
+    max: 10
+    failGrade: 3
+    groups:
+    - name: exam
+      cap: 0.3
+    - name: homework
+      minRatio: 0.5
+`
+
+func parseGrading(t *testing.T, rounding string) *Deadlines {
+	t.Helper()
+	deadlines, err := parseV2([]byte(fmt.Sprintf(gradeYaml, rounding)))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+	return deadlines
+}
+
+func checkGrade(t *testing.T, scoring *Scoring, marks map[string]GroupMark, expected float64) {
+	t.Helper()
+	grade := scoring.FinalGrade(marks)
+	if grade == nil {
+		t.Fatal("Expected final grade")
+	}
+	if *grade != expected {
+		t.Fatalf("Invalid grade for marks %+v: %g, expected: %g", marks, *grade, expected)
+	}
+}
+
+func makeMarks(homework, exam int) map[string]GroupMark {
+	return map[string]GroupMark{
+		"homework": {Mark: 0.7 * float64(homework) / 100, Score: homework, MaxScore: 100},
+		"exam":     {Mark: 0.4 * float64(exam) / 10, Score: exam, MaxScore: 10},
+	}
+}
+
+func TestFinalGrade(t *testing.T) {
+	round := parseGrading(t, "round")
+	checkGrade(t, &round.Scoring, makeMarks(100, 0), 7)
+	checkGrade(t, &round.Scoring, makeMarks(50, 0), 4)
+	checkGrade(t, &round.Scoring, makeMarks(100, 5), 9)
+	// Exam contribution is capped
+	checkGrade(t, &round.Scoring, makeMarks(100, 10), 10)
+	checkGrade(t, &round.Scoring, makeMarks(60, 10), 7)
+	// Less than half of homework
+	checkGrade(t, &round.Scoring, makeMarks(49, 10), 3)
+
+	floor := parseGrading(t, "floor")
+	checkGrade(t, &floor.Scoring, makeMarks(100, 1), 7)
+	checkGrade(t, &floor.Scoring, makeMarks(100, 5), 9)
+
+	ceil := parseGrading(t, "ceil")
+	checkGrade(t, &ceil.Scoring, makeMarks(100, 1), 8)
+	checkGrade(t, &ceil.Scoring, makeMarks(100, 0), 7)
+
+	if grade := (&Scoring{}).FinalGrade(makeMarks(100, 10)); grade != nil {
+		t.Fatal("Expected no grade without grading section, got", *grade)
+	}
+}
+
+func TestGradeThresholds(t *testing.T) {
+	scoring := &Scoring{
+		Groups: []ScoringGroup{{Name: "homework", Weight: 1, MaxScore: 100}},
+		Grade: &Grading{
+			Thresholds: []GradeThreshold{
+				{Mark: 0.8, Grade: 5},
+				{Mark: 0, Grade: 2},
+				{Mark: 0.6, Grade: 4},
+				{Mark: 0.4, Grade: 3},
+			},
+		},
+	}
+
+	marks := func(score int) map[string]GroupMark {
+		return map[string]GroupMark{"homework": {Mark: float64(score) / 100, Score: score, MaxScore: 100}}
+	}
+	checkGrade(t, scoring, marks(0), 2)
+	checkGrade(t, scoring, marks(39), 2)
+	checkGrade(t, scoring, marks(40), 3)
+	checkGrade(t, scoring, marks(79), 4)
+	checkGrade(t, scoring, marks(80), 5)
+	checkGrade(t, scoring, marks(100), 5)
+}
+
+func TestUnknownRounding(t *testing.T) {
+	if _, err := parseV2([]byte(fmt.Sprintf(gradeYaml, "banker"))); err == nil {
+		t.Fatal("Expected error for unknown rounding mode")
+	}
+}
@@ -0,0 +1,283 @@
+package deadlines
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+type Diagnostic struct {
+	// Line is 1-based line in the deadlines file, zero if unknown
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.Message
+	}
+	return fmt.Sprintf("line %d: %s", d.Line, d.Message)
+}
+
+type LintOptions struct {
+	// Format is either v1 or v2, see GroupConfig.DeadlinesFormat
+	Format string
+	// ExpectedWeight is the expected sum of scoring group weights, zero disables the check
+	ExpectedWeight float64
+}
+
+const weightEpsilon = 1e-6
+
+// Lint parses deadlines in the same way the fetcher does and reports all found problems.
+func Lint(body []byte, options LintOptions) []Diagnostic {
+	l := &linter{options: options}
+
+	root := &yaml.Node{}
+	if err := yaml.Unmarshal(body, root); err != nil {
+		l.reportError(err)
+		return l.diagnostics
+	}
+	l.root = root
+	if options.Format == "v2" {
+		l.assignments = []interface{}{"assignments"}
+	}
+
+	l.lintPolicyKinds()
+
+	deadlines, err := parse(body, options.Format)
+	if err != nil {
+		l.reportError(err)
+		return l.diagnostics
+	}
+
+	l.lintScoring(deadlines)
+	l.lintAssignments(deadlines)
+	return l.diagnostics
+}
+
+type linter struct {
+	options     LintOptions
+	root        *yaml.Node
+	assignments []interface{}
+	diagnostics []Diagnostic
+}
+
+func (l *linter) report(line int, format string, args ...interface{}) {
+	l.diagnostics = append(l.diagnostics, Diagnostic{
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+var yamlErrorLineRe = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+func (l *linter) reportError(err error) {
+	// Unknown policies are already reported by lintPolicyKinds with proper lines
+	var unknownPolicy *UnknownPolicyError
+	if errors.As(err, &unknownPolicy) && len(l.diagnostics) > 0 {
+		return
+	}
+
+	var typeError *yamlv2.TypeError
+	if errors.As(err, &typeError) {
+		for _, msg := range typeError.Errors {
+			l.reportMessage(msg)
+		}
+		return
+	}
+	l.reportMessage(errors.Cause(err).Error())
+}
+
+func (l *linter) reportMessage(msg string) {
+	if submatches := yamlErrorLineRe.FindStringSubmatch(msg); len(submatches) == 3 {
+		line, _ := strconv.Atoi(submatches[1])
+		l.report(line, "%s", submatches[2])
+		return
+	}
+	l.report(0, "%s", msg)
+}
+
+// line returns the line of the node at the given path of mapping keys and sequence indices
+func (l *linter) line(path ...interface{}) int {
+	node := l.root
+	line := 0
+	for node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	for _, key := range path {
+		if node == nil {
+			return line
+		}
+		switch key := key.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return line
+			}
+			var next *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+			node = next
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return line
+			}
+			node = node.Content[key]
+			line = node.Line
+		}
+	}
+	return line
+}
+
+func (l *linter) assignmentLine(path ...interface{}) int {
+	return l.line(append(append([]interface{}{}, l.assignments...), path...)...)
+}
+
+func (l *linter) lintPolicyKinds() {
+	if l.options.Format != "v2" {
+		return
+	}
+
+	policies := l.root
+	for _, key := range []string{"scoring", "policies"} {
+		policies = findMappingValue(policies, key)
+	}
+	if policies == nil || policies.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, policy := range policies.Content {
+		kind := findMappingValue(policy, "kind")
+		if kind == nil {
+			l.report(policy.Line, "policy kind is not specified")
+		} else if newScoringPolicy(kind.Value) == nil {
+			l.report(kind.Line, "unknown policy kind %q", kind.Value)
+		}
+	}
+}
+
+func findMappingValue(node *yaml.Node, key string) *yaml.Node {
+	for node != nil && node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func (l *linter) lintScoring(deadlines *Deadlines) {
+	if l.options.Format != "v2" {
+		return
+	}
+	scoring := &deadlines.Scoring
+
+	totalWeight := 0.0
+	for i := range scoring.Groups {
+		group := &scoring.Groups[i]
+		totalWeight += group.Weight
+		if _, found := deadlines.policies[group.Policy]; !found {
+			l.report(l.line("scoring", "groups", i, "policy"), "scoring group %q references unknown policy %q", group.Name, group.Policy)
+		}
+		if group.MinScore > group.MaxScore {
+			l.report(l.line("scoring", "groups", i, "minScore"), "scoring group %q requires %d points out of %d", group.Name, group.MinScore, group.MaxScore)
+		}
+	}
+
+	if scoring.DefaultGroup != "" {
+		if _, found := deadlines.groups[scoring.DefaultGroup]; !found {
+			l.report(l.line("scoring", "defaultGroup"), "unknown default scoring group %q", scoring.DefaultGroup)
+		}
+	}
+
+	if scoring.Grade != nil {
+		for i := range scoring.Grade.Groups {
+			group := &scoring.Grade.Groups[i]
+			if _, found := deadlines.groups[group.Name]; !found {
+				l.report(l.line("scoring", "grade", "groups", i, "name"), "grade references unknown scoring group %q", group.Name)
+			}
+			if group.MinRatio > 1 {
+				l.report(l.line("scoring", "grade", "groups", i, "minRatio"), "scoring group %q requires more than its max score", group.Name)
+			}
+		}
+	}
+
+	for i := range scoring.RetakePolicies {
+		policy := &scoring.RetakePolicies[i]
+		switch policy.Kind {
+		case RetakeKindDeadline:
+			if policy.Deadline == nil {
+				l.report(l.line("scoring", "retakePolicies", i), "retake policy %q has no deadline", policy.Name)
+			}
+		case RetakeKindGroups:
+			for j, title := range policy.Groups {
+				if !deadlines.HasTaskGroup(title) {
+					l.report(l.line("scoring", "retakePolicies", i, "groups", j), "retake policy %q references unknown task group %q", policy.Name, title)
+				}
+			}
+		}
+	}
+
+	if l.options.ExpectedWeight > 0 && math.Abs(totalWeight-l.options.ExpectedWeight) > weightEpsilon {
+		l.report(l.line("scoring", "groups"), "scoring group weights sum up to %g, expected %g", totalWeight, l.options.ExpectedWeight)
+	}
+}
+
+func (l *linter) lintAssignments(deadlines *Deadlines) {
+	seen := make(map[string]string)
+
+	for i := range deadlines.Assignments {
+		group := &deadlines.Assignments[i]
+
+		if group.Deadline.IsZero() {
+			l.report(l.assignmentLine(i), "task group %q has no deadline", group.Title)
+		} else if group.Start.After(group.Deadline.Time) {
+			l.report(l.assignmentLine(i, "start"), "task group %q starts after its deadline", group.Title)
+		}
+
+		if group.Group != "" {
+			if _, found := deadlines.groups[group.Group]; !found {
+				l.report(l.assignmentLine(i, "group"), "task group %q references unknown scoring group %q", group.Title, group.Group)
+			}
+		}
+
+		for j := range group.Tasks {
+			task := &group.Tasks[j]
+
+			if prev, found := seen[task.Task]; found {
+				l.report(l.assignmentLine(i, "tasks", j), "duplicate task %q, already defined in task group %q", task.Task, prev)
+			} else {
+				seen[task.Task] = group.Title
+			}
+
+			if task.Group != "" {
+				if _, found := deadlines.groups[task.Group]; !found {
+					l.report(l.assignmentLine(i, "tasks", j, "group"), "task %q references unknown scoring group %q", task.Task, task.Group)
+				}
+			}
+			if task.Policy != "" {
+				if _, found := deadlines.policies[task.Policy]; !found {
+					l.report(l.assignmentLine(i, "tasks", j, "policy"), "task %q references unknown policy %q", task.Task, task.Policy)
+				}
+			}
+			if task.Deadline != nil && group.Start.After(task.Deadline.Time) {
+				l.report(l.assignmentLine(i, "tasks", j, "deadline"), "task %q deadline is before task group %q start", task.Task, group.Title)
+			}
+		}
+	}
+}
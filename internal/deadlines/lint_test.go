@@ -0,0 +1,164 @@
+package deadlines
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const brokenDeadlinesYaml = `
+scoring:
+  policies:
+  - name: soft
+    kind: exp
+    spec:
+      multiplier: 120h
+      threshold: 0.3
+  groups:
+  - name: weekly
+    weight: 4.0
+    policy: soft
+  - name: large
+    weight: 5.0
+    policy: missing
+  defaultGroup: weekly
+
+assignments:
+- title:    01-move
+  start:    20-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: 100
+- title:    02-move
+  start:    10-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  group:    nope
+  tasks:
+    - task: dedup
+      score: 100
+    - task: deque
+      score: 100
+      policy: hard
+`
+
+func TestLint(t *testing.T) {
+	diagnostics := Lint([]byte(brokenDeadlinesYaml), LintOptions{Format: "v2", ExpectedWeight: 10.0})
+
+	expected := []Diagnostic{
+		{15, `scoring group "large" references unknown policy "missing"`},
+		{9, `scoring group weights sum up to 9, expected 10`},
+		{20, `task group "01-move" starts after its deadline`},
+		{28, `task group "02-move" references unknown scoring group "nope"`},
+		{30, `duplicate task "dedup", already defined in task group "01-move"`},
+		{34, `task "deque" references unknown policy "hard"`},
+	}
+	if diff := cmp.Diff(expected, diagnostics); diff != "" {
+		t.Fatalf("Unexpected diagnostics (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestLintSyntaxErrors(t *testing.T) {
+	diagnostics := Lint([]byte(`
+scoring:
+  policies:
+  - name: soft
+    kind: magic
+assignments:
+- title: 01-move
+  tasks:
+    - task: dedup
+      score: abc
+`), LintOptions{Format: "v2"})
+
+	expected := []Diagnostic{
+		{5, `unknown policy kind "magic"`},
+	}
+	if diff := cmp.Diff(expected, diagnostics); diff != "" {
+		t.Fatalf("Unexpected diagnostics (-expected +actual):\n%s", diff)
+	}
+
+	diagnostics = Lint([]byte(`
+assignments:
+- title: 01-move
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: abc
+`), LintOptions{Format: "v2"})
+
+	expected = []Diagnostic{
+		{7, "cannot unmarshal !!str `abc` into int"},
+	}
+	if diff := cmp.Diff(expected, diagnostics); diff != "" {
+		t.Fatalf("Unexpected diagnostics (-expected +actual):\n%s", diff)
+	}
+}
+
+func TestLintGrade(t *testing.T) {
+	body := `
+scoring:
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: homework
+    weight: 1.0
+    policy: hard
+  grade:
+    groups:
+    - name: homework
+      minRatio: 1.5
+    - name: exam
+      cap: 0.3
+`
+	diagnostics := Lint([]byte(body), LintOptions{Format: "v2"})
+	expected := []Diagnostic{
+		{Line: 13, Message: `scoring group "homework" requires more than its max score`},
+		{Line: 14, Message: `grade references unknown scoring group "exam"`},
+	}
+	if diff := cmp.Diff(expected, diagnostics); diff != "" {
+		t.Fatalf("Unexpected diagnostics (-want +got):\n%s", diff)
+	}
+}
+
+func TestLintRequirements(t *testing.T) {
+	body := `
+scoring:
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: homework
+    weight: 1.0
+    policy: hard
+    minScore: 300
+  defaultGroup: homework
+
+assignments:
+- title:    01-move
+  start:    10-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: 100
+      required: true
+    - task: deque
+      score: 100
+`
+	diagnostics := Lint([]byte(body), LintOptions{Format: "v2"})
+	expected := []Diagnostic{
+		{Line: 10, Message: `scoring group "homework" requires 300 points out of 200`},
+	}
+	if diff := cmp.Diff(expected, diagnostics); diff != "" {
+		t.Fatalf("Unexpected diagnostics (-want +got):\n%s", diff)
+	}
+
+	deadlines, err := parseV2([]byte(body))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+	if tasks := deadlines.Assignments[0].Tasks; !tasks[0].Required || tasks[1].Required {
+		t.Fatalf("Invalid required flags: %+v", tasks)
+	}
+}
@@ -0,0 +1,39 @@
+package deadlines
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	fetchErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "notmanytask",
+		Subsystem: "deadlines",
+		Name:      "fetch_errors_total",
+		Help:      "Failed deadlines fetches per group.",
+	}, []string{"group"})
+
+	fetchAgeDesc = prometheus.NewDesc(
+		"notmanytask_deadlines_fetch_age_seconds",
+		"Time since the last successful deadlines fetch per group.",
+		[]string{"group"}, nil,
+	)
+)
+
+// Describe implements prometheus.Collector, the fetch age is calculated on scrape.
+func (f *Fetcher) Describe(ch chan<- *prometheus.Desc) {
+	ch <- fetchAgeDesc
+}
+
+// Collect skips the groups whose deadlines were never fetched.
+func (f *Fetcher) Collect(ch chan<- prometheus.Metric) {
+	now := time.Now()
+	for _, status := range f.GroupStatuses() {
+		if status.LastSuccess.IsZero() {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(fetchAgeDesc, prometheus.GaugeValue, now.Sub(status.LastSuccess).Seconds(), status.Group)
+	}
+}
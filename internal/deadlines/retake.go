@@ -0,0 +1,138 @@
+package deadlines
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// RetakeKindFloor guarantees the share of the task score for every solved task
+	RetakeKindFloor = "floor"
+	// RetakeKindGroups opens retake-only task groups with their own deadlines
+	RetakeKindGroups = "groups"
+	// RetakeKindCap scores solved tasks regardless of deadlines, but up to the share of the task score
+	RetakeKindCap = "cap"
+	// RetakeKindDeadline moves all the earlier deadlines to the retake deadline
+	RetakeKindDeadline = "deadline"
+)
+
+// DefaultRetakePolicy is assigned to the students who had a retake before named policies.
+// Unless defined explicitly, it floors task scores at Scoring.RetakePenalty.
+const DefaultRetakePolicy = "default"
+
+type RetakePolicy struct {
+	Name string
+	Kind string
+	// Share is the minimal (floor) or the maximal (cap) share of the task score
+	Share float64 `yaml:",omitempty"`
+	// Deadline is the retake deadline (deadline)
+	Deadline *Date `yaml:",omitempty"`
+	// Groups are titles of the retake-only task groups (groups)
+	Groups []string `yaml:",omitempty"`
+}
+
+type UnknownRetakePolicyError struct {
+	Kind string
+}
+
+func (e *UnknownRetakePolicyError) Error() string {
+	return fmt.Sprintf("unknown retake policy %s", e.Kind)
+}
+
+func (p *RetakePolicy) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type P RetakePolicy
+	if err := unmarshal((*P)(p)); err != nil {
+		return err
+	}
+
+	switch p.Kind {
+	case RetakeKindFloor, RetakeKindGroups, RetakeKindCap, RetakeKindDeadline:
+		return nil
+	default:
+		return &UnknownRetakePolicyError{p.Kind}
+	}
+}
+
+// Apply adjusts the regular task score of the retaking student.
+func (p *RetakePolicy) Apply(maxScore, score int) int {
+	if p == nil {
+		return score
+	}
+
+	share := int(float64(maxScore) * p.Share)
+	switch p.Kind {
+	case RetakeKindFloor:
+		return int(math.Max(float64(score), float64(share)))
+	case RetakeKindCap:
+		return int(math.Min(float64(score), float64(share)))
+	default:
+		return score
+	}
+}
+
+// IgnoresDeadlines reports whether solved tasks are scored regardless of the deadlines.
+func (p *RetakePolicy) IgnoresDeadlines() bool {
+	return p != nil && p.Kind == RetakeKindCap
+}
+
+// Description is a short human readable summary of the policy.
+func (p *RetakePolicy) Description() string {
+	switch p.Kind {
+	case RetakeKindFloor:
+		return fmt.Sprintf("Solved tasks are worth at least %g%% of the score", p.Share*100)
+	case RetakeKindCap:
+		return fmt.Sprintf("Solved tasks are worth up to %g%% of the score regardless of deadlines", p.Share*100)
+	case RetakeKindDeadline:
+		if p.Deadline == nil {
+			return "Regular deadlines"
+		}
+		return fmt.Sprintf("All deadlines are moved to %s", p.Deadline.String())
+	case RetakeKindGroups:
+		return fmt.Sprintf("Retake task groups: %s", strings.Join(p.Groups, ", "))
+	default:
+		return p.Kind
+	}
+}
+
+// GetRetakePolicy returns the retake policy by name, nil if the student has no retake.
+func (d *Deadlines) GetRetakePolicy(name string) *RetakePolicy {
+	if name == "" {
+		return nil
+	}
+	for i := range d.Scoring.RetakePolicies {
+		if d.Scoring.RetakePolicies[i].Name == name {
+			return &d.Scoring.RetakePolicies[i]
+		}
+	}
+	if name == DefaultRetakePolicy && d.Scoring.RetakePenalty > 0 {
+		return &RetakePolicy{
+			Name:  DefaultRetakePolicy,
+			Kind:  RetakeKindFloor,
+			Share: d.Scoring.RetakePenalty,
+		}
+	}
+	return nil
+}
+
+// GetRetakeGroupPolicy returns name of the retake policy the task group is available to, empty for regular groups.
+func (d *Deadlines) GetRetakeGroupPolicy(group *TaskGroup) string {
+	for i := range d.Scoring.RetakePolicies {
+		policy := &d.Scoring.RetakePolicies[i]
+		if policy.Kind != RetakeKindGroups {
+			continue
+		}
+		for _, title := range policy.Groups {
+			if title == group.Title {
+				return policy.Name
+			}
+		}
+	}
+	return ""
+}
+
+// IsTaskGroupAvailable reports whether the student with the given retake policy may solve tasks of the group.
+func (d *Deadlines) IsTaskGroupAvailable(group *TaskGroup, retake *RetakePolicy) bool {
+	owner := d.GetRetakeGroupPolicy(group)
+	return owner == "" || (retake != nil && retake.Name == owner)
+}
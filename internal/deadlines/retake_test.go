@@ -0,0 +1,116 @@
+package deadlines
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const retakeYaml = `
+scoring:
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: homework
+    weight: 1.0
+    policy: hard
+  defaultGroup: homework
+  retakePenalty: 0.3
+  retakePolicies:
+  - name: floor
+    kind: floor
+    share: 0.5
+  - name: cap
+    kind: cap
+    share: 0.6
+  - name: summer
+    kind: deadline
+    deadline: 01-07-2022 23:59
+  - name: extra
+    kind: groups
+    groups: [retake]
+
+assignments:
+- title:    regular
+  start:    10-09-2021 13:00
+  deadline: 17-09-2021 19:00
+  tasks:
+    - task: dedup
+      score: 100
+- title:    retake
+  start:    10-06-2022 13:00
+  deadline: 20-06-2022 19:00
+  tasks:
+    - task: deque
+      score: 200
+`
+
+func TestRetakePolicies(t *testing.T) {
+	deadlines, err := parseV2([]byte(retakeYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+
+	if policy := deadlines.GetRetakePolicy(""); policy != nil {
+		t.Fatal("Expected no retake policy, got", policy.Name)
+	}
+	if policy := deadlines.GetRetakePolicy("missing"); policy != nil {
+		t.Fatal("Expected no retake policy, got", policy.Name)
+	}
+
+	floor := deadlines.GetRetakePolicy("floor")
+	if score := floor.Apply(100, 10); score != 50 {
+		t.Fatalf("Invalid floor score: %d", score)
+	}
+	if score := floor.Apply(100, 80); score != 80 {
+		t.Fatalf("Invalid floor score: %d", score)
+	}
+
+	capped := deadlines.GetRetakePolicy("cap")
+	if !capped.IgnoresDeadlines() {
+		t.Fatal("Capped retake should ignore deadlines")
+	}
+	if score := capped.Apply(100, 100); score != 60 {
+		t.Fatalf("Invalid capped score: %d", score)
+	}
+
+	// Legacy retake students are floored at the retake penalty
+	legacy := deadlines.GetRetakePolicy(DefaultRetakePolicy)
+	if legacy == nil || legacy.Kind != RetakeKindFloor || legacy.Apply(100, 0) != 30 {
+		t.Fatalf("Invalid default retake policy: %+v", legacy)
+	}
+
+	summer := deadlines.GetRetakePolicy("summer")
+	if expected := time.Date(2022, 7, 1, 23, 59, 0, 0, getDefaultLocation()); !summer.Deadline.Equal(expected) {
+		t.Fatalf("Invalid retake deadline: %v", summer.Deadline)
+	}
+
+	regular, retake := &deadlines.Assignments[0], &deadlines.Assignments[1]
+	if !deadlines.IsTaskGroupAvailable(regular, nil) || !deadlines.IsTaskGroupAvailable(regular, floor) {
+		t.Fatal("Regular task group should be available to everyone")
+	}
+	if deadlines.IsTaskGroupAvailable(retake, nil) || deadlines.IsTaskGroupAvailable(retake, floor) {
+		t.Fatal("Retake task group should be available only with its policy")
+	}
+	if !deadlines.IsTaskGroupAvailable(retake, deadlines.GetRetakePolicy("extra")) {
+		t.Fatal("Retake task group should be available with its policy")
+	}
+
+	if maxScore := deadlines.GetScoringGroup(regular).MaxScore; maxScore != 100 {
+		t.Fatalf("Retake task groups should not count towards max score, got %d", maxScore)
+	}
+}
+
+func TestUnknownRetakePolicy(t *testing.T) {
+	_, err := parseV2([]byte(`
+scoring:
+  retakePolicies:
+  - name: magic
+    kind: magic
+`))
+	unknown := &UnknownRetakePolicyError{}
+	if !errors.As(err, &unknown) || unknown.Kind != "magic" {
+		t.Fatalf("Expected unknown retake policy error, got %v", err)
+	}
+}
@@ -3,11 +3,18 @@ package deadlines
 import (
 	"fmt"
 	"math"
+	"sort"
 	"time"
 )
 
 type ScoringPolicy interface {
 	Score(maxScore int, deadline time.Time, submitTime time.Time) int
+	// Share is the multiplier of the max score given for the submission, Score truncates the scaled max score
+	Share(deadline time.Time, submitTime time.Time) float64
+}
+
+func scaleScore(maxScore int, share float64) int {
+	return int(float64(maxScore) * share)
 }
 
 type ScoringPolicySpec struct {
@@ -21,6 +28,16 @@ type ScoringGroup struct {
 	Weight   float64
 	MaxScore int `yaml:"maxScore"`
 	Policy   string
+	// MinScore is the minimal total score in the group required to pass the course
+	MinScore int `yaml:"minScore,omitempty"`
+	// Aggregation is one of sum (default), best, dropLowest or cap, see AggregationSum
+	Aggregation string `yaml:",omitempty"`
+	// Count is the number of the best tasks counted or the lowest tasks dropped
+	Count int `yaml:",omitempty"`
+	// Clamp limits the group total with MaxScore, otherwise extra credit is allowed
+	Clamp bool `yaml:",omitempty"`
+	// Selection is one of firstSuccess (default), latest or best, see SelectionFirstSuccess
+	Selection string `yaml:",omitempty"`
 }
 
 type Scoring struct {
@@ -29,6 +46,14 @@ type Scoring struct {
 	DefaultGroup  string  `yaml:"defaultGroup"`
 	FinalDeadline *Date   `yaml:"finalDeadline"`
 	RetakePenalty float64 `yaml:"retakePenalty"`
+	// LateDays is the number of whole days each student may be late without penalty
+	LateDays int `yaml:"lateDays"`
+	// Grade is optional conversion of the final mark to the university grade scale
+	Grade *Grading `yaml:",omitempty"`
+	// FailedMarkCap caps the final mark of students with unmet requirements, nil disables the cap
+	FailedMarkCap *float64 `yaml:"failedMarkCap,omitempty"`
+	// RetakePolicies are assigned to students by name, see DefaultRetakePolicy
+	RetakePolicies []RetakePolicy `yaml:"retakePolicies,omitempty"`
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -39,12 +64,16 @@ type ExponentialScore struct {
 }
 
 func (s *ExponentialScore) Score(maxScore int, deadline, submitTime time.Time) int {
+	return scaleScore(maxScore, s.Share(deadline, submitTime))
+}
+
+func (s *ExponentialScore) Share(deadline, submitTime time.Time) float64 {
 	if submitTime.Before(deadline) {
-		return maxScore
+		return 1.0
 	}
 	deltaSeconds := submitTime.Sub(deadline).Seconds()
 	exp := deltaSeconds / s.Multiplier.Seconds()
-	return int(math.Max(s.Threshold, 1.0/math.Exp(exp)) * float64(maxScore))
+	return math.Max(s.Threshold, 1.0/math.Exp(exp))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -55,8 +84,12 @@ type LinearScore struct {
 }
 
 func (s *LinearScore) Score(maxScore int, deadline, submitTime time.Time) int {
+	return scaleScore(maxScore, s.Share(deadline, submitTime))
+}
+
+func (s *LinearScore) Share(deadline, submitTime time.Time) float64 {
 	if submitTime.Before(deadline) {
-		return maxScore
+		return 1.0
 	}
 	finish := deadline.Add(s.After)
 
@@ -65,12 +98,120 @@ func (s *LinearScore) Score(maxScore int, deadline, submitTime time.Time) int {
 		delta := submitTime.Sub(deadline).Seconds() / s.After.Seconds()
 		mult = (1.0 - delta) + (s.Multiplier * delta)
 	}
+	return mult
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type ScoreStep struct {
+	After      time.Duration
+	Multiplier float64
+}
+
+// StepScore multiplies the score by the multiplier of the last passed step.
+// Steps are sorted by After on unmarshal.
+type StepScore struct {
+	Steps []ScoreStep
+}
+
+func (s *StepScore) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type S StepScore
+	if err := unmarshal((*S)(s)); err != nil {
+		return err
+	}
+	sort.SliceStable(s.Steps, func(i, j int) bool {
+		return s.Steps[i].After < s.Steps[j].After
+	})
+	return nil
+}
 
-	return int(float64(maxScore) * mult)
+func (s *StepScore) Score(maxScore int, deadline, submitTime time.Time) int {
+	return scaleScore(maxScore, s.Share(deadline, submitTime))
+}
+
+func (s *StepScore) Share(deadline, submitTime time.Time) float64 {
+	if submitTime.Before(deadline) {
+		return 1.0
+	}
+	delta := submitTime.Sub(deadline)
+
+	mult := 1.0
+	for _, step := range s.Steps {
+		if delta < step.After {
+			break
+		}
+		mult = step.Multiplier
+	}
+	return mult
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// HardScore gives full score until the deadline and nothing after it.
+type HardScore struct {
+}
+
+func (s *HardScore) Score(maxScore int, deadline, submitTime time.Time) int {
+	return scaleScore(maxScore, s.Share(deadline, submitTime))
+}
+
+func (s *HardScore) Share(deadline, submitTime time.Time) float64 {
+	if submitTime.After(deadline) {
+		return 0.0
+	}
+	return 1.0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BonusScore gives Bonus * maxScore extra points for each whole Period between the submission and the deadline.
+// Extra points are capped at MaxBonus * maxScore. Submissions after the deadline get the plain max score.
+type BonusScore struct {
+	Period   time.Duration
+	Bonus    float64
+	MaxBonus float64 `yaml:"maxBonus"`
+}
+
+func (s *BonusScore) Score(maxScore int, deadline, submitTime time.Time) int {
+	return scaleScore(maxScore, s.Share(deadline, submitTime))
+}
+
+func (s *BonusScore) Share(deadline, submitTime time.Time) float64 {
+	if submitTime.After(deadline) || s.Period <= 0 {
+		return 1.0
+	}
+
+	periods := math.Floor(deadline.Sub(submitTime).Seconds() / s.Period.Seconds())
+	return 1.0 + math.Min(s.MaxBonus, s.Bonus*periods)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type UnknownPolicyError struct {
+	Kind string
+}
+
+func (e *UnknownPolicyError) Error() string {
+	return fmt.Sprintf("unknown policy %s", e.Kind)
+}
+
+func newScoringPolicy(kind string) ScoringPolicy {
+	switch kind {
+	case "exp":
+		return new(ExponentialScore)
+	case "linear":
+		return new(LinearScore)
+	case "steps":
+		return new(StepScore)
+	case "hard":
+		return new(HardScore)
+	case "bonus":
+		return new(BonusScore)
+	default:
+		return nil
+	}
+}
+
 type yamlNode struct {
 	unmarshal func(interface{}) error
 }
@@ -93,13 +234,14 @@ func (s *ScoringPolicySpec) UnmarshalYAML(unmarshal func(interface{}) error) err
 	}
 	*s = ScoringPolicySpec(obj.S)
 
-	switch s.Kind {
-	case "exp":
-		s.Policy = new(ExponentialScore)
-	case "linear":
-		s.Policy = new(LinearScore)
-	default:
-		return fmt.Errorf("unknown policy %s", s.Kind)
+	s.Policy = newScoringPolicy(s.Kind)
+	if s.Policy == nil {
+		return &UnknownPolicyError{s.Kind}
+	}
+
+	// Some policies do not need any spec
+	if obj.Spec.unmarshal == nil {
+		return nil
 	}
 	return obj.Spec.unmarshal(s.Policy)
 }
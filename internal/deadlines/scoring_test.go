@@ -0,0 +1,82 @@
+package deadlines
+
+import (
+	"testing"
+	"time"
+)
+
+const policiesYaml = `
+scoring:
+  policies:
+  - name: steps
+    kind: steps
+    spec:
+      steps:
+      - after: 24h
+        multiplier: 0.5
+      - after: 1h
+        multiplier: 0.8
+      - after: 168h
+        multiplier: 0
+  - name: hard
+    kind: hard
+  - name: bonus
+    kind: bonus
+    spec:
+      period: 24h
+      bonus: 0.05
+      maxBonus: 0.1
+`
+
+func checkPolicyScore(t *testing.T, policy ScoringPolicy, deadline time.Time, delta time.Duration, expected int) {
+	t.Helper()
+	score := policy.Score(100, deadline, deadline.Add(delta))
+	if score != expected {
+		t.Fatalf("Invalid score for submission at deadline%+v: %d, expected: %d", delta, score, expected)
+	}
+}
+
+func TestScoringPolicies(t *testing.T) {
+	deadlines, err := parseV2([]byte(policiesYaml))
+	if err != nil {
+		t.Fatal("Failed to parse deadlines:", err)
+	}
+	deadline := time.Date(2021, 9, 17, 19, 0, 0, 0, getDefaultLocation())
+
+	steps := deadlines.policies["steps"]
+	checkPolicyScore(t, steps, deadline, -time.Minute, 100)
+	checkPolicyScore(t, steps, deadline, 0, 100)
+	checkPolicyScore(t, steps, deadline, time.Hour-time.Second, 100)
+	checkPolicyScore(t, steps, deadline, time.Hour, 80)
+	checkPolicyScore(t, steps, deadline, 24*time.Hour-time.Second, 80)
+	checkPolicyScore(t, steps, deadline, 24*time.Hour, 50)
+	checkPolicyScore(t, steps, deadline, 168*time.Hour, 0)
+	checkPolicyScore(t, steps, deadline, 1000*time.Hour, 0)
+
+	hard := deadlines.policies["hard"]
+	checkPolicyScore(t, hard, deadline, -time.Hour, 100)
+	checkPolicyScore(t, hard, deadline, 0, 100)
+	checkPolicyScore(t, hard, deadline, time.Second, 0)
+	checkPolicyScore(t, hard, deadline, time.Hour, 0)
+
+	bonus := deadlines.policies["bonus"]
+	checkPolicyScore(t, bonus, deadline, time.Hour, 100)
+	checkPolicyScore(t, bonus, deadline, time.Second, 100)
+	checkPolicyScore(t, bonus, deadline, 0, 100)
+	checkPolicyScore(t, bonus, deadline, -24*time.Hour+time.Second, 100)
+	checkPolicyScore(t, bonus, deadline, -24*time.Hour, 105)
+	checkPolicyScore(t, bonus, deadline, -48*time.Hour, 110)
+	checkPolicyScore(t, bonus, deadline, -240*time.Hour, 110)
+}
+
+func TestUnknownScoringPolicy(t *testing.T) {
+	_, err := parseV2([]byte(`
+scoring:
+  policies:
+  - name: magic
+    kind: magic
+`))
+	if err == nil {
+		t.Fatal("Expected error for unknown policy kind")
+	}
+}
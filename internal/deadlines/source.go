@@ -0,0 +1,209 @@
+package deadlines
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// errNotModified is returned by sources if the deadlines file was not changed since the last read
+var errNotModified = errors.New("Deadlines were not modified")
+
+// source reads raw deadlines file.
+// Sources are not safe for concurrent use, each group owns its own source.
+type source interface {
+	read(ctx context.Context) ([]byte, error)
+	// invalidate forces the next read to return the file even if it was not modified.
+	// Used when the previous file failed to parse.
+	invalidate()
+}
+
+// newSource creates source from the GroupConfig.DeadlinesURL:
+//   - http(s)://host/path for remote files, conditional requests are used
+//   - file:///path/to/deadlines.yml for local files
+//   - git:///path/to/repo//path/in/repo.yml for local git working copies, uncommitted changes are read as well
+//   - git:///path/to/repo//path/in/repo.yml?ref=origin/main&fetch=true for the file committed at the ref
+func newSource(rawURL string) (source, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse deadlines url")
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		return &httpSource{url: rawURL}, nil
+	case "file":
+		return &fileSource{path: u.Path}, nil
+	case "git":
+		repo, path, found := strings.Cut(u.Path, "//")
+		if !found || path == "" {
+			return nil, errors.Errorf("git deadlines url %s should look like git:///path/to/repo//path/in/repo.yml", rawURL)
+		}
+		ref, fetch := u.Query().Get("ref"), u.Query().Get("fetch") == "true"
+		if ref == "" {
+			if fetch {
+				return nil, errors.Errorf("git deadlines url %s should specify ref to fetch", rawURL)
+			}
+			// Working copy is read as is, so staff can test deadlines before committing them
+			return &fileSource{path: filepath.Join(repo, path)}, nil
+		}
+		return &gitSource{
+			repo:  repo,
+			path:  path,
+			ref:   ref,
+			fetch: fetch,
+		}, nil
+	default:
+		return nil, errors.Errorf("unsupported deadlines url scheme %q", u.Scheme)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type httpSource struct {
+	url string
+
+	// Validators of the last successful response, used for conditional requests
+	etag         string
+	lastModified string
+}
+
+func (s *httpSource) read(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, http.NoBody)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to create request")
+	}
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	if s.lastModified != "" {
+		req.Header.Set("If-Modified-Since", s.lastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch deadlines")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, errNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch deadlines: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read response")
+	}
+
+	s.etag = resp.Header.Get("ETag")
+	s.lastModified = resp.Header.Get("Last-Modified")
+	return body, nil
+}
+
+func (s *httpSource) invalidate() {
+	s.etag = ""
+	s.lastModified = ""
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type fileSource struct {
+	path string
+
+	modTime time.Time
+	size    int64
+}
+
+func (s *fileSource) read(ctx context.Context) ([]byte, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to stat deadlines file")
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil, errNotModified
+	}
+
+	body, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read deadlines file")
+	}
+
+	s.modTime = info.ModTime()
+	s.size = info.Size()
+	return body, nil
+}
+
+func (s *fileSource) invalidate() {
+	s.modTime = time.Time{}
+	s.size = 0
+}
+
+// watchPath returns the directory to watch for changes.
+// Directory is watched instead of the file itself to survive atomic renames done by editors.
+func (s *fileSource) watchPath() string {
+	return filepath.Dir(s.path)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// gitSource reads the file committed at the ref, working copy changes are ignored.
+type gitSource struct {
+	repo  string
+	path  string
+	ref   string
+	fetch bool
+
+	commit string
+}
+
+func (s *gitSource) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", s.repo}, args...)...)
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func (s *gitSource) read(ctx context.Context) ([]byte, error) {
+	if s.fetch {
+		if _, err := s.git(ctx, "fetch", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+
+	out, err := s.git(ctx, "rev-parse", "--verify", s.ref+"^{commit}")
+	if err != nil {
+		return nil, err
+	}
+	commit := strings.TrimSpace(string(out))
+	if commit == s.commit {
+		return nil, errNotModified
+	}
+
+	body, err := s.git(ctx, "show", commit+":"+s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.commit = commit
+	return body, nil
+}
+
+func (s *gitSource) invalidate() {
+	s.commit = ""
+}
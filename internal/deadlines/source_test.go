@@ -0,0 +1,115 @@
+package deadlines
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func checkSourceRead(t *testing.T, src source, expected string) {
+	t.Helper()
+	body, err := src.read(context.Background())
+	if err != nil {
+		t.Fatal("Failed to read source:", err)
+	}
+	if string(body) != expected {
+		t.Fatalf("Invalid body: %q, expected: %q", body, expected)
+	}
+}
+
+func checkSourceNotModified(t *testing.T, src source) {
+	t.Helper()
+	_, err := src.read(context.Background())
+	if !errors.Is(err, errNotModified) {
+		t.Fatalf("Expected not modified error, got %v", err)
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deadlines.yml")
+	if err := os.WriteFile(path, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := newSource("file://" + path)
+	if err != nil {
+		t.Fatal("Failed to create source:", err)
+	}
+
+	checkSourceRead(t, src, "first")
+	checkSourceNotModified(t, src)
+
+	if err = os.WriteFile(path, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	checkSourceRead(t, src, "second")
+
+	src.invalidate()
+	checkSourceRead(t, src, "second")
+}
+
+func TestGitSource(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, out)
+		}
+	}
+	commit := func(content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(repo, "deadlines"), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, "deadlines", "hse.yml"), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", content, "--date", time.Now().Format(time.RFC3339))
+	}
+
+	git("init", "-q")
+	commit("first")
+
+	worktree, err := newSource("git://" + repo + "//deadlines/hse.yml")
+	if err != nil {
+		t.Fatal("Failed to create source:", err)
+	}
+	committed, err := newSource("git://" + repo + "//deadlines/hse.yml?ref=HEAD")
+	if err != nil {
+		t.Fatal("Failed to create source:", err)
+	}
+
+	checkSourceRead(t, worktree, "first")
+	checkSourceNotModified(t, worktree)
+	checkSourceRead(t, committed, "first")
+	checkSourceNotModified(t, committed)
+
+	commit("second")
+	checkSourceRead(t, worktree, "second")
+	checkSourceRead(t, committed, "second")
+
+	// Uncommitted changes are visible only in the working copy
+	if err = os.WriteFile(filepath.Join(repo, "deadlines", "hse.yml"), []byte("uncommitted"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	checkSourceRead(t, worktree, "uncommitted")
+	checkSourceNotModified(t, committed)
+}
+
+func TestInvalidSource(t *testing.T) {
+	for _, url := range []string{"ftp://example.com/deadlines.yml", "git:///repo/without/path.yml", "git:///repo//path.yml?fetch=true"} {
+		if _, err := newSource(url); err == nil {
+			t.Fatalf("Expected error for url %s", url)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// formulaPrefixes make spreadsheet applications evaluate the csv cell as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// escapeFormula keeps text cells, e.g. names filled by students, from being evaluated as formulas.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune(formulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func writeCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	for _, row := range table {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			if cell.IsNumber {
+				record[i] = cell.Text
+			} else {
+				record[i] = escapeFormula(cell.Text)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
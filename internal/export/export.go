@@ -0,0 +1,229 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bigredeye/notmanytask/internal/scorer"
+)
+
+const (
+	ColumnName   Column = "name"
+	ColumnLogin  Column = "login"
+	ColumnTasks  Column = "tasks"
+	ColumnTotals Column = "totals"
+	ColumnScore  Column = "score"
+	ColumnMark   Column = "mark"
+	ColumnGrade  Column = "grade"
+)
+
+// Column is the group of columns in the exported table, e.g. tasks expands to the column per task.
+type Column string
+
+var DefaultColumns = []Column{ColumnName, ColumnLogin, ColumnTasks, ColumnTotals, ColumnScore, ColumnMark, ColumnGrade}
+
+const (
+	NameLastFirst    NameFormat = "last-first"
+	NameFirstLast    NameFormat = "first-last"
+	NameLastInitials NameFormat = "last-initials"
+)
+
+type NameFormat string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+	FormatODS  Format = "ods"
+)
+
+type Format string
+
+func (f Format) ContentType() string {
+	switch f {
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case FormatODS:
+		return "application/vnd.oasis.opendocument.spreadsheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+func ParseFormat(format string) (Format, error) {
+	switch Format(format) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatXLSX, FormatODS:
+		return Format(format), nil
+	default:
+		return "", fmt.Errorf("unknown export format %s", format)
+	}
+}
+
+type Options struct {
+	Columns    []Column
+	NameFormat NameFormat
+}
+
+// ParseOptions parses comma separated columns, empty values select defaults.
+func ParseOptions(columns, nameFormat string) (*Options, error) {
+	options := &Options{
+		Columns:    DefaultColumns,
+		NameFormat: NameLastFirst,
+	}
+
+	if columns != "" {
+		options.Columns = make([]Column, 0)
+		for _, column := range strings.Split(columns, ",") {
+			switch Column(column) {
+			case ColumnName, ColumnLogin, ColumnTasks, ColumnTotals, ColumnScore, ColumnMark, ColumnGrade:
+				options.Columns = append(options.Columns, Column(column))
+			default:
+				return nil, fmt.Errorf("unknown column %s", column)
+			}
+		}
+	}
+
+	switch NameFormat(nameFormat) {
+	case "":
+	case NameLastFirst, NameFirstLast, NameLastInitials:
+		options.NameFormat = NameFormat(nameFormat)
+	default:
+		return nil, fmt.Errorf("unknown name format %s", nameFormat)
+	}
+
+	return options, nil
+}
+
+func (o *Options) formatName(user *scorer.User) string {
+	switch o.NameFormat {
+	case NameFirstLast:
+		return user.FirstName + " " + user.LastName
+	case NameLastInitials:
+		if initial := []rune(user.FirstName); len(initial) > 0 {
+			return user.LastName + " " + string(initial[0]) + "."
+		}
+		return user.LastName
+	default:
+		return user.LastName + " " + user.FirstName
+	}
+}
+
+// Cell is either a text or a number, spreadsheets keep numbers numeric.
+type Cell struct {
+	Text     string
+	Number   float64
+	IsNumber bool
+}
+
+func text(value string) Cell {
+	return Cell{Text: value}
+}
+
+func number(value float64) Cell {
+	return Cell{Text: fmt.Sprint(value), Number: value, IsNumber: true}
+}
+
+// Table is the exported sheet, the first row is the header.
+type Table [][]Cell
+
+type taskColumn struct {
+	group string
+	task  string
+}
+
+// listColumns collects task groups and tasks in the order of the first appearance, users may have different groups.
+func listColumns(standings *scorer.Standings) (groups []string, tasks []taskColumn) {
+	seenGroups := make(map[string]bool)
+	seenTasks := make(map[taskColumn]bool)
+	for _, user := range standings.Users {
+		for _, group := range user.Groups {
+			if !seenGroups[group.Title] {
+				seenGroups[group.Title] = true
+				groups = append(groups, group.Title)
+			}
+			for _, task := range group.Tasks {
+				column := taskColumn{group.Title, task.Task}
+				if !seenTasks[column] {
+					seenTasks[column] = true
+					tasks = append(tasks, column)
+				}
+			}
+		}
+	}
+	return
+}
+
+func BuildTable(standings *scorer.Standings, options *Options) Table {
+	groups, tasks := listColumns(standings)
+
+	header := make([]Cell, 0)
+	for _, column := range options.Columns {
+		switch column {
+		case ColumnTasks:
+			for _, task := range tasks {
+				header = append(header, text(task.task))
+			}
+		case ColumnTotals:
+			for _, group := range groups {
+				header = append(header, text(group+" total"))
+			}
+		default:
+			header = append(header, text(string(column)))
+		}
+	}
+	table := Table{header}
+
+	for _, user := range standings.Users {
+		taskScores := make(map[taskColumn]int)
+		groupScores := make(map[string]int)
+		for _, group := range user.Groups {
+			groupScores[group.Title] = group.Score
+			for _, task := range group.Tasks {
+				taskScores[taskColumn{group.Title, task.Task}] = task.Score
+			}
+		}
+
+		row := make([]Cell, 0, len(header))
+		for _, column := range options.Columns {
+			switch column {
+			case ColumnName:
+				row = append(row, text(options.formatName(&user.User)))
+			case ColumnLogin:
+				row = append(row, text(user.User.GitlabLogin))
+			case ColumnTasks:
+				for _, task := range tasks {
+					row = append(row, number(float64(taskScores[task])))
+				}
+			case ColumnTotals:
+				for _, group := range groups {
+					row = append(row, number(float64(groupScores[group])))
+				}
+			case ColumnScore:
+				row = append(row, number(float64(user.Score)))
+			case ColumnMark:
+				row = append(row, number(user.FinalMark))
+			case ColumnGrade:
+				if user.FinalGrade != nil {
+					row = append(row, number(*user.FinalGrade))
+				} else {
+					row = append(row, text(""))
+				}
+			}
+		}
+		table = append(table, row)
+	}
+	return table
+}
+
+func Write(w io.Writer, format Format, table Table) error {
+	switch format {
+	case FormatXLSX:
+		return writeXLSX(w, table)
+	case FormatODS:
+		return writeODS(w, table)
+	default:
+		return writeCSV(w, table)
+	}
+}
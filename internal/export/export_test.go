@@ -0,0 +1,114 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bigredeye/notmanytask/internal/scorer"
+)
+
+func testStandings() *scorer.Standings {
+	grade := 8.0
+	return &scorer.Standings{
+		Users: []*scorer.UserScores{
+			{
+				Groups: []scorer.ScoredTaskGroup{
+					{Title: "week1", Score: 150, Tasks: []scorer.ScoredTask{{Task: "a", Score: 100}, {Task: "b", Score: 50}}},
+				},
+				Score:      150,
+				FinalMark:  7.5,
+				FinalGrade: &grade,
+				User:       scorer.User{FirstName: "Ivan", LastName: "Ivanov", GitlabLogin: "ivanov"},
+			},
+			{
+				Groups: []scorer.ScoredTaskGroup{
+					{Title: "week1", Score: 0, Tasks: []scorer.ScoredTask{{Task: "a"}}},
+					{Title: "retake", Score: 30, Tasks: []scorer.ScoredTask{{Task: "c", Score: 30}}},
+				},
+				Score:     30,
+				FinalMark: 1.5,
+				User:      scorer.User{FirstName: "Petr", LastName: "Petrov", GitlabLogin: "petrov"},
+			},
+		},
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	options, err := ParseOptions("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err = Write(&buf, FormatCSV, BuildTable(testStandings(), options)); err != nil {
+		t.Fatal(err)
+	}
+	expected := `name,login,a,b,c,week1 total,retake total,score,mark,grade
+Ivanov Ivan,ivanov,100,50,0,150,0,150,7.5,8
+Petrov Petr,petrov,0,0,30,0,30,30,1.5,
+`
+	if buf.String() != expected {
+		t.Fatalf("Unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestExportCSVFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	table := Table{{text("=HYPERLINK(\"http://evil\")"), text("@SUM(A1)"), text("-1+2"), text("Ivanov"), number(-1.5)}}
+	if err := Write(&buf, FormatCSV, table); err != nil {
+		t.Fatal(err)
+	}
+	expected := `"'=HYPERLINK(""http://evil"")",'@SUM(A1),'-1+2,Ivanov,-1.5
+`
+	if buf.String() != expected {
+		t.Fatalf("Unexpected csv:\n%s", buf.String())
+	}
+}
+
+func TestExportOptions(t *testing.T) {
+	options, err := ParseOptions("name,mark", "last-initials")
+	if err != nil {
+		t.Fatal(err)
+	}
+	table := BuildTable(testStandings(), options)
+	if len(table[1]) != 2 || table[1][0].Text != "Ivanov I." || table[1][1].Number != 7.5 {
+		t.Fatalf("Unexpected row: %v", table[1])
+	}
+
+	if _, err = ParseOptions("name,email", ""); err == nil {
+		t.Fatal("Expected unknown column error")
+	}
+	if _, err = ParseOptions("", "middle"); err == nil {
+		t.Fatal("Expected unknown name format error")
+	}
+}
+
+func TestExportSpreadsheets(t *testing.T) {
+	options, _ := ParseOptions("", "")
+	table := BuildTable(testStandings(), options)
+
+	for _, format := range []Format{FormatXLSX, FormatODS} {
+		var buf bytes.Buffer
+		if err := Write(&buf, format, table); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatalf("Invalid %s archive: %v", format, err)
+		}
+		if format == FormatODS && (archive.File[0].Name != "mimetype" || archive.File[0].Method != zip.Store) {
+			t.Fatalf("Invalid ods mimetype entry: %+v", archive.File[0].FileHeader)
+		}
+	}
+
+	for index, name := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if column := xlsxColumn(index); column != name {
+			t.Fatalf("Expected column %s for %d, got %s", name, index, column)
+		}
+	}
+	if !strings.Contains(xlsxSheet(Table{{text("a<b")}}), "a&lt;b") {
+		t.Fatal("Expected escaped text")
+	}
+}
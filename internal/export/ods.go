@@ -0,0 +1,56 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Minimal OpenDocument spreadsheet, the mimetype file must be the first one and must not be compressed.
+const (
+	odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+	odsManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+<manifest:file-entry manifest:full-path="/" manifest:media-type="application/vnd.oasis.opendocument.spreadsheet"/>
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+)
+
+func odsContent(table Table) string {
+	var content strings.Builder
+	content.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2">
+<office:body><office:spreadsheet><table:table table:name="Standings">`)
+	for _, row := range table {
+		content.WriteString(`<table:table-row>`)
+		for _, cell := range row {
+			if cell.IsNumber {
+				fmt.Fprintf(&content, `<table:table-cell office:value-type="float" office:value="%s"><text:p>%s</text:p></table:table-cell>`,
+					strconv.FormatFloat(cell.Number, 'g', -1, 64), escapeXML(cell.Text))
+			} else {
+				fmt.Fprintf(&content, `<table:table-cell office:value-type="string"><text:p>%s</text:p></table:table-cell>`, escapeXML(cell.Text))
+			}
+		}
+		content.WriteString(`</table:table-row>`)
+	}
+	content.WriteString(`</table:table></office:spreadsheet></office:body></office:document-content>`)
+	return content.String()
+}
+
+func writeODS(w io.Writer, table Table) error {
+	archive := zip.NewWriter(w)
+	mimeType, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = mimeType.Write([]byte(odsMimeType)); err != nil {
+		return err
+	}
+
+	return writeZipFiles(archive, [][2]string{
+		{"META-INF/manifest.xml", odsManifest},
+		{"content.xml", odsContent(table)},
+	})
+}
@@ -0,0 +1,27 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"strings"
+)
+
+func escapeXML(value string) string {
+	var builder strings.Builder
+	_ = xml.EscapeText(&builder, []byte(value))
+	return builder.String()
+}
+
+// writeZipFiles writes files in the given order, both formats care about the order of the first files.
+func writeZipFiles(archive *zip.Writer, files [][2]string) error {
+	for _, file := range files {
+		writer, err := archive.Create(file[0])
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write([]byte(file[1])); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -28,7 +28,11 @@ type Client struct {
 }
 
 func NewClient(conf *config.Config, logger *zap.Logger) (*Client, error) {
-	client, err := gitlab.NewClient(conf.GitLab.Api.Token, gitlab.WithBaseURL(conf.GitLab.BaseURL))
+	client, err := gitlab.NewClient(
+		conf.GitLab.Api.Token,
+		gitlab.WithBaseURL(conf.GitLab.BaseURL),
+		gitlab.WithHTTPClient(&http.Client{Transport: errorsCountingTransport{http.DefaultTransport}}),
+	)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to create gitlab client")
 	}
@@ -0,0 +1,62 @@
+package gitlab
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	pipelinesFetchDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "notmanytask",
+		Subsystem: "pipelines",
+		Name:      "fetch_duration_seconds",
+		Help:      "Duration of the pipelines fetcher iteration, fetcher is either all or fresh.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 14),
+	}, []string{"fetcher"})
+
+	freshPipelines = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "notmanytask",
+		Subsystem: "pipelines",
+		Name:      "fresh_queue_size",
+		Help:      "Number of pipelines reported by graders which are still polled.",
+	})
+
+	apiErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "notmanytask",
+		Subsystem: "gitlab",
+		Name:      "api_errors_total",
+		Help:      "Failed GitLab API requests by status code, transport failures are labeled as error.",
+	}, []string{"code"})
+
+	projectsPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "notmanytask",
+		Subsystem: "projects",
+		Name:      "pending_users",
+		Help:      "Number of users waiting in the projects maker queue.",
+	})
+
+	projectsFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "notmanytask",
+		Subsystem: "projects",
+		Name:      "failures_total",
+		Help:      "Failed project initializations.",
+	})
+)
+
+// errorsCountingTransport counts every failed attempt, including the ones retried by the gitlab client.
+type errorsCountingTransport struct {
+	next http.RoundTripper
+}
+
+func (t errorsCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		apiErrors.WithLabelValues("error").Inc()
+	} else if resp.StatusCode >= http.StatusBadRequest {
+		apiErrors.WithLabelValues(strconv.Itoa(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/xanzy/go-gitlab"
 	"go.uber.org/zap"
 
@@ -19,12 +20,16 @@ type PipelinesFetcher struct {
 	*Client
 
 	logger *zap.Logger
-	db     *database.DataBase
+	db     database.Store
 
-	fresh sync.Map
+	// fresh maps pipelines reported by graders to their last fetched status
+	fresh     sync.Map
+	listeners []func(project string)
+	// statuses keeps the pipeline statuses seen by fetchAllPipelines, nil until the first iteration
+	statuses map[qualifiedPipelineID]string
 }
 
-func NewPipelinesFetcher(client *Client, db *database.DataBase) (*PipelinesFetcher, error) {
+func NewPipelinesFetcher(client *Client, db database.Store) (*PipelinesFetcher, error) {
 	return &PipelinesFetcher{
 		Client: client,
 		logger: client.logger.Named("pipelines"),
@@ -32,6 +37,18 @@ func NewPipelinesFetcher(client *Client, db *database.DataBase) (*PipelinesFetch
 	}, nil
 }
 
+// OnStatusChange registers the callback called when a pipeline changes its status or a new pipeline is found.
+// Callbacks must be registered before the fetcher is started.
+func (p *PipelinesFetcher) OnStatusChange(callback func(project string)) {
+	p.listeners = append(p.listeners, callback)
+}
+
+func (p *PipelinesFetcher) notifyStatusChange(project string) {
+	for _, listener := range p.listeners {
+		listener(project)
+	}
+}
+
 func (p *PipelinesFetcher) Run(ctx context.Context) {
 	interval := p.config.PullIntervals.Pipelines
 	if interval == nil {
@@ -72,7 +89,7 @@ type qualifiedPipelineID struct {
 
 func (p *PipelinesFetcher) AddFresh(id int, project string) error {
 	p.logger.Info("Added fresh pipeline", lf.ProjectName(project), lf.PipelineID(id))
-	p.fresh.Store(&qualifiedPipelineID{project, id}, true)
+	p.fresh.Store(qualifiedPipelineID{project, id}, "")
 	return nil
 }
 
@@ -111,9 +128,18 @@ func (p *PipelinesFetcher) addPipeline(projectName string, pipeline *gitlab.Pipe
 }
 
 func (p *PipelinesFetcher) fetchAllPipelines() {
+	timer := prometheus.NewTimer(pipelinesFetchDuration.WithLabelValues("all"))
+	defer timer.ObserveDuration()
+
 	p.logger.Debug("Start pipelines fetcher iteration")
 	defer p.logger.Debug("Finish pipelines fetcher iteration")
 
+	// Pipelines found by the first iteration are already accounted by the materializer
+	initial := p.statuses == nil
+	if initial {
+		p.statuses = make(map[qualifiedPipelineID]string)
+	}
+
 	err := p.forEachProject(func(project *gitlab.Project) error {
 		p.logger.Debug("Found project", lf.ProjectName(project.Name))
 		options := &gitlab.ListProjectPipelinesOptions{}
@@ -128,7 +154,14 @@ func (p *PipelinesFetcher) fetchAllPipelines() {
 				p.logger.Debug("Found pipeline", lf.ProjectName(project.Name), lf.PipelineID(pipeline.ID), lf.PipelineStatus(pipeline.Status))
 				if err = p.addPipeline(project.Name, pipeline); err != nil {
 					p.logger.Error("Failed to add pipeline", zap.Error(err), lf.ProjectName(project.Name), lf.PipelineID(pipeline.ID))
+					continue
+				}
+
+				id := qualifiedPipelineID{project.Name, pipeline.ID}
+				if status, found := p.statuses[id]; found && status != pipeline.Status || !found && !initial {
+					p.notifyStatusChange(project.Name)
 				}
+				p.statuses[id] = pipeline.Status
 			}
 
 			if resp.CurrentPage >= resp.TotalPages {
@@ -174,13 +207,25 @@ func (p *PipelinesFetcher) forEachProject(callback func(project *gitlab.Project)
 }
 
 func (p *PipelinesFetcher) fetchFreshPipelines() {
+	timer := prometheus.NewTimer(pipelinesFetchDuration.WithLabelValues("fresh"))
+	defer timer.ObserveDuration()
+
+	fetched := 0
 	removed := make([]interface{}, 0)
-	p.fresh.Range(func(key, _ interface{}) bool {
-		id := key.(*qualifiedPipelineID)
+	p.fresh.Range(func(key, status interface{}) bool {
+		id := key.(qualifiedPipelineID)
+		fetched++
 		info, err := p.fetch(id.id, id.project)
 		if err != nil {
 			p.logger.Error("Failed to fetch pipeline", zap.Error(err))
-		} else if info.Status != models.PipelineStatusRunning {
+			return true
+		}
+
+		if info.Status != status.(string) {
+			p.fresh.Store(id, info.Status)
+			p.notifyStatusChange(id.project)
+		}
+		if info.Status != models.PipelineStatusRunning {
 			p.logger.Info("Fetched fresh pipeline", lf.ProjectName(id.project), lf.PipelineID(id.id), lf.PipelineStatus(info.Status))
 			removed = append(removed, id)
 		}
@@ -190,6 +235,7 @@ func (p *PipelinesFetcher) fetchFreshPipelines() {
 	for _, id := range removed {
 		p.fresh.Delete(id)
 	}
+	freshPipelines.Set(float64(fetched - len(removed)))
 }
 
 const (
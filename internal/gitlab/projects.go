@@ -13,15 +13,16 @@ type ProjectsMaker struct {
 	*Client
 
 	logger *zap.Logger
-	db     *database.DataBase
+	db     database.Store
 	users  chan *models.User
 }
 
-func NewProjectsMaker(client *Client, db *database.DataBase) (*ProjectsMaker, error) {
+func NewProjectsMaker(client *Client, db database.Store) (*ProjectsMaker, error) {
 	return &ProjectsMaker{client, client.logger.Named("projects"), db, make(chan *models.User, 4)}, nil
 }
 
 func (p ProjectsMaker) AsyncPrepareProject(user *models.User) {
+	projectsPending.Inc()
 	p.users <- user
 }
 
@@ -36,11 +37,13 @@ func (p ProjectsMaker) Run(ctx context.Context) {
 	for {
 		select {
 		case user := <-p.users:
+			projectsPending.Dec()
 			p.logger.Info("Got user from in-proc channel",
 				zap.Intp("gitlab_id", user.GitlabID),
 				zap.Stringp("gitlab_login", user.GitlabLogin),
 			)
 			if !p.maybeInitializeProject(user) {
+				projectsPending.Inc()
 				p.users <- user
 			}
 		case <-tick.C:
@@ -87,6 +90,7 @@ func (p ProjectsMaker) maybeInitializeProject(user *models.User) bool {
 	err := p.InitializeProject(user)
 	if err != nil {
 		log.Error("Failed to initialize project", zap.Error(err))
+		projectsFailures.Inc()
 		// TODO(BigRedEye): nice backoff
 		time.Sleep(time.Second * 1)
 		return false
@@ -99,6 +103,7 @@ func (p ProjectsMaker) maybeInitializeProject(user *models.User) bool {
 	err = p.db.SetUserRepository(user)
 	if err != nil {
 		log.Error("Failed to set user repo", zap.Error(err))
+		projectsFailures.Inc()
 		return false
 	}
 
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	AuditActionOverride        = "override"
+	AuditActionExtension       = "extension"
+	AuditActionRemoveExtension = "removeExtension"
+	AuditActionChangeGroup     = "changeGroup"
+	AuditActionRetake          = "retake"
+	AuditActionReport          = "report"
+	AuditActionCreateFlag      = "createFlag"
+	AuditActionSubmitFlag      = "submitFlag"
+	AuditActionRole            = "role"
+	AuditActionCreateToken     = "createToken"
+	AuditActionRevokeToken     = "revokeToken"
+)
+
+// AuditRecord is the append-only log entry of the change made by an API token or a user.
+type AuditRecord struct {
+	ID        uint      `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"index"`
+	// Actor is token:<token name>, user:<gitlab login> or cli for the server subcommands
+	Actor  string `gorm:"index"`
+	Action string `gorm:"index"`
+	// Target is the gitlab login of the affected student, empty if there is none
+	Target   string `gorm:"index"`
+	OldValue string
+	NewValue string
+}
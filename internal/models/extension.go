@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Extension struct {
+	gorm.Model
+
+	GitlabLogin string `gorm:"uniqueIndex:idx_extensions"`
+	// Task is either a task name or a task group title
+	Task string `gorm:"uniqueIndex:idx_extensions"`
+
+	Deadline time.Time
+	Reason   string
+}
@@ -7,4 +7,6 @@ type Flag struct {
 	Task        string  `gorm:"index"`
 	GitlabLogin *string `gorm:"index"`
 	CreatedAt   time.Time
+	// SubmittedAt is nil for the flags submitted before it was tracked
+	SubmittedAt *time.Time
 }
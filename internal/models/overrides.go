@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -13,3 +15,16 @@ type OverriddenScore struct {
 	Score  int
 	Status PipelineStatus
 }
+
+// OverrideHistory is the append-only log of overrides used to restore standings at any moment.
+type OverrideHistory struct {
+	ID          uint   `gorm:"primaryKey"`
+	GitlabLogin string `gorm:"index"`
+	Task        string
+
+	Score  int
+	Status PipelineStatus
+	// Removed is set if the override was removed at CreatedAt
+	Removed   bool
+	CreatedAt time.Time `gorm:"index"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 )
 
@@ -22,4 +23,19 @@ type Pipeline struct {
 	Task      string `gorm:"index"`
 	Status    PipelineStatus
 	StartedAt time.Time
+	// UpdatedAt changes only if the pipeline was actually changed
+	UpdatedAt time.Time `gorm:"index"`
+
+	// Fraction is the share of the task score reported by the grader, nil if the task is scored all-or-nothing
+	Fraction    *float64
+	TestsPassed int
+	TestsTotal  int
+}
+
+// ScoreFraction returns the share of the task score earned by the pipeline.
+func (p *Pipeline) ScoreFraction() float64 {
+	if p.Fraction == nil {
+		return 1.0
+	}
+	return math.Max(0.0, math.Min(1.0, *p.Fraction))
 }
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// MaterializedScores keeps the serialized scorer results of the student, see scorer.Materializer.
+type MaterializedScores struct {
+	GitlabLogin string `gorm:"primaryKey"`
+	GroupName   string `gorm:"index"`
+	Scores      []byte
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeReport        Scope = "report"
+	ScopeFlag          Scope = "flag"
+	ScopeOverride      Scope = "override"
+	ScopeReadStandings Scope = "read-standings"
+	ScopeManageUsers   Scope = "manage-users"
+)
+
+const apiTokenScopesDelim = ","
+
+// Scope limits the API endpoints available to the token.
+type Scope string
+
+var AllScopes = []Scope{ScopeReport, ScopeFlag, ScopeOverride, ScopeReadStandings, ScopeManageUsers}
+
+func ParseScope(scope string) (Scope, error) {
+	for _, known := range AllScopes {
+		if Scope(scope) == known {
+			return known, nil
+		}
+	}
+	return "", fmt.Errorf("unknown scope %s", scope)
+}
+
+// APIToken is the personal API token, only the token hash is stored.
+type APIToken struct {
+	ID        uint `gorm:"primaryKey"`
+	CreatedAt time.Time
+	Name      string `gorm:"uniqueIndex"`
+	Hash      string `gorm:"uniqueIndex" json:"-"`
+	// Scopes are comma separated, see Scope
+	Scopes string
+	// GroupName restricts the token to the students of the group, empty means any group
+	GroupName  string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func HashAPIToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
+
+func (t *APIToken) ScopeList() []Scope {
+	scopes := make([]Scope, 0)
+	for _, scope := range strings.Split(t.Scopes, apiTokenScopesDelim) {
+		if scope != "" {
+			scopes = append(scopes, Scope(scope))
+		}
+	}
+	return scopes
+}
+
+func (t *APIToken) SetScopes(scopes []Scope) {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	t.Scopes = strings.Join(names, apiTokenScopesDelim)
+}
+
+func (t *APIToken) HasScope(scope Scope) bool {
+	for _, granted := range t.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsGroup reports whether the token may act on the students of the group.
+func (t *APIToken) AllowsGroup(group string) bool {
+	return t.GroupName == "" || t.GroupName == group
+}
+
+// Active reports whether the token is neither revoked nor expired.
+func (t *APIToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
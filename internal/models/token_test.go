@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAPITokenScopes(t *testing.T) {
+	token := &APIToken{}
+	token.SetScopes([]Scope{ScopeReport, ScopeReadStandings})
+	if token.Scopes != "report,read-standings" {
+		t.Fatalf("Invalid scopes: %s", token.Scopes)
+	}
+
+	for scope, expected := range map[Scope]bool{
+		ScopeReport:        true,
+		ScopeReadStandings: true,
+		ScopeOverride:      false,
+		ScopeManageUsers:   false,
+		Scope("report,"):   false,
+	} {
+		if token.HasScope(scope) != expected {
+			t.Errorf("Token has scope %s: %v, expected %v", scope, !expected, expected)
+		}
+	}
+
+	if len((&APIToken{}).ScopeList()) != 0 {
+		t.Errorf("Empty token has scopes")
+	}
+	if _, err := ParseScope("admin"); err == nil {
+		t.Errorf("Unknown scope is parsed")
+	}
+}
+
+func TestAPITokenActive(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+
+	for _, test := range []struct {
+		name     string
+		token    APIToken
+		expected bool
+	}{
+		{name: "no expiry", token: APIToken{}, expected: true},
+		{name: "not expired", token: APIToken{ExpiresAt: &future}, expected: true},
+		{name: "expired", token: APIToken{ExpiresAt: &past}, expected: false},
+		{name: "expires now", token: APIToken{ExpiresAt: &now}, expected: false},
+		{name: "revoked", token: APIToken{ExpiresAt: &future, RevokedAt: &past}, expected: false},
+	} {
+		if active := test.token.Active(now); active != test.expected {
+			t.Errorf("Token %s is active: %v, expected %v", test.name, active, test.expected)
+		}
+	}
+}
+
+func TestAPITokenAllowsGroup(t *testing.T) {
+	if !(&APIToken{}).AllowsGroup("hse") {
+		t.Errorf("Unrestricted token does not allow group")
+	}
+	restricted := &APIToken{GroupName: "hse"}
+	if !restricted.AllowsGroup("hse") || restricted.AllowsGroup("ysda") {
+		t.Errorf("Restricted token allows wrong groups")
+	}
+}
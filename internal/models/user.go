@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
 )
 
@@ -19,7 +21,46 @@ type User struct {
 	LastName   string `gorm:"uniqueIndex:idx_name"`
 	GroupName  string `gorm:"uniqueIndex:idx_name"`
 	TelegramID *int64
-	HasRetake  bool
+	// RetakePolicy is the name of the retake policy from the deadlines scoring section, empty if there is no retake
+	RetakePolicy string
+	// Role is assigned via API, config roles are applied on top of it, see config.RolesConfig
+	Role Role
+}
+
+const (
+	RoleStudent Role = "student"
+	RoleTA      Role = "ta"
+	RoleAdmin   Role = "admin"
+)
+
+// Role is one of student, ta or admin, empty role means student.
+type Role string
+
+func ParseRole(role string) (Role, error) {
+	switch Role(role) {
+	case "", RoleStudent:
+		return RoleStudent, nil
+	case RoleTA, RoleAdmin:
+		return Role(role), nil
+	default:
+		return "", fmt.Errorf("unknown role %s", role)
+	}
+}
+
+func (r Role) level() int {
+	switch r {
+	case RoleAdmin:
+		return 2
+	case RoleTA:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// AtLeast reports whether the role has all privileges of the given role.
+func (r Role) AtLeast(role Role) bool {
+	return r.level() >= role.level()
 }
 
 type Session struct {
@@ -0,0 +1,92 @@
+package scorer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+const (
+	ExplanationSourcePipeline = "pipeline"
+	ExplanationSourceFlag     = "flag"
+	ExplanationSourceOverride = "override"
+)
+
+// Explanation describes how the task score was calculated.
+type Explanation struct {
+	Source     string
+	PipelineID int                   `json:",omitempty"`
+	Status     models.PipelineStatus `json:",omitempty"`
+
+	SubmittedAt time.Time
+	Deadline    time.Time
+	LateDays    int `json:",omitempty"`
+
+	Policy string `json:",omitempty"`
+	// Fraction is the partial credit reported by the grader
+	Fraction float64
+	// Multiplier is applied by the scoring policy to late submissions
+	Multiplier float64
+	// FinalDeadline is set if the submission was after the final deadline
+	FinalDeadline bool `json:",omitempty"`
+	// Retake is the name of the retake policy which changed the score
+	Retake string `json:",omitempty"`
+}
+
+// Late returns how late the submission was, zero if it was in time.
+func (e *Explanation) Late() time.Duration {
+	if !e.SubmittedAt.After(e.Deadline) {
+		return 0
+	}
+	return e.SubmittedAt.Sub(e.Deadline)
+}
+
+func (e *Explanation) String() string {
+	if e.Source == ExplanationSourceOverride {
+		return fmt.Sprintf("Score has been set by the teacher, status %s", e.Status)
+	}
+
+	lines := make([]string, 0)
+	if e.Source == ExplanationSourceFlag {
+		lines = append(lines, "Flag submitted")
+	} else {
+		lines = append(lines, fmt.Sprintf("Pipeline #%d: %s", e.PipelineID, e.Status))
+	}
+	if e.Status != models.PipelineStatusSuccess {
+		return strings.Join(lines, "\n")
+	}
+
+	submitted := fmt.Sprintf("Submitted at %s", formatTime(e.SubmittedAt))
+	if late := e.Late(); late > 0 {
+		submitted += fmt.Sprintf(", %s after the deadline %s", late.Round(time.Minute), formatTime(e.Deadline))
+	} else {
+		submitted += fmt.Sprintf(", before the deadline %s", formatTime(e.Deadline))
+	}
+	lines = append(lines, submitted)
+
+	if e.LateDays > 0 {
+		lines = append(lines, fmt.Sprintf("%d late day(s) used", e.LateDays))
+	}
+	if e.Fraction < 1.0 {
+		lines = append(lines, fmt.Sprintf("Partial credit: %g%%", e.Fraction*100))
+	}
+	if e.Policy == "" {
+		lines = append(lines, "No scoring policy")
+	} else {
+		lines = append(lines, fmt.Sprintf("Policy %s: multiplier %.2f", e.Policy, e.Multiplier))
+	}
+	if e.FinalDeadline {
+		lines = append(lines, "Submitted after the final deadline")
+	}
+	if e.Retake != "" {
+		lines = append(lines, fmt.Sprintf("Retake policy %s applied", e.Retake))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatTime(t time.Time) string {
+	return (&deadlines.Date{Time: t}).String()
+}
@@ -0,0 +1,117 @@
+package scorer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func TestExplanationMultiplier(t *testing.T) {
+	scorer, _ := newTestScorer(t, someStrangeDeadlines)
+	d := scorer.deadlines.GroupDeadlines(testGroup)
+	deadline := d.Assignments[0].Deadline.Time
+	final := &deadlines.Date{Time: deadline.Add(24 * time.Hour)}
+	// Tiny task score makes the truncated score useless for the multiplier reconstruction
+	task := &deadlines.Task{Task: "tiny", Score: 3}
+
+	for _, test := range []struct {
+		name          string
+		policy        string
+		submit        string
+		finalDeadline *deadlines.Date
+		score         int
+		multiplier    float64
+	}{
+		{name: "in time", policy: "linear", submit: "19-07-1969 23:00", score: 3, multiplier: 1.0},
+		{name: "half of the linear period", policy: "linear", submit: "24-07-1969 08:17", score: 2, multiplier: 0.75},
+		{name: "exp threshold", policy: "exp", submit: "19-07-1979 23:00", score: 0, multiplier: 0.3},
+		{name: "final deadline", policy: "linear", submit: "22-07-1969 10:00", finalDeadline: final, score: 0, multiplier: 0.0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			current := *d
+			current.Scoring.FinalDeadline = test.finalDeadline
+
+			explanation := &Explanation{}
+			score := scorer.scorePipeline(findPolicy(d, test.policy), &current, nil, task, deadline, deadline, makePipeline(test.submit, models.PipelineStatusSuccess), explanation)
+			if score != test.score {
+				t.Errorf("Invalid score %d, expected %d", score, test.score)
+			}
+			if diff := explanation.Multiplier - test.multiplier; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("Invalid multiplier %g, expected %g", explanation.Multiplier, test.multiplier)
+			}
+			if explanation.FinalDeadline != (test.finalDeadline != nil) {
+				t.Errorf("Final deadline is not explained")
+			}
+		})
+	}
+}
+
+func TestExplanationString(t *testing.T) {
+	deadline := time.Date(2022, time.March, 1, 20, 59, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name        string
+		explanation Explanation
+		lines       []string
+	}{
+		{
+			name:        "override",
+			explanation: Explanation{Source: ExplanationSourceOverride, Status: models.PipelineStatusSuccess},
+			lines:       []string{"Score has been set by the teacher, status success"},
+		},
+		{
+			name:        "failed",
+			explanation: Explanation{Source: ExplanationSourcePipeline, PipelineID: 42, Status: models.PipelineStatusFailed},
+			lines:       []string{"Pipeline #42: failed"},
+		},
+		{
+			name: "late partial credit",
+			explanation: Explanation{
+				Source:      ExplanationSourcePipeline,
+				PipelineID:  42,
+				Status:      models.PipelineStatusSuccess,
+				SubmittedAt: deadline.Add(90 * time.Minute),
+				Deadline:    deadline,
+				LateDays:    1,
+				Policy:      "linear",
+				Fraction:    0.5,
+				Multiplier:  0.75,
+			},
+			lines: []string{
+				"Pipeline #42: success",
+				"Submitted at 01-03-2022 22:29 UTC, 1h30m0s after the deadline 01-03-2022 20:59 UTC",
+				"1 late day(s) used",
+				"Partial credit: 50%",
+				"Policy linear: multiplier 0.75",
+			},
+		},
+		{
+			name: "flag after final deadline",
+			explanation: Explanation{
+				Source:        ExplanationSourceFlag,
+				Status:        models.PipelineStatusSuccess,
+				SubmittedAt:   deadline.Add(-time.Hour),
+				Deadline:      deadline,
+				Fraction:      1.0,
+				FinalDeadline: true,
+				Retake:        "summer",
+			},
+			lines: []string{
+				"Flag submitted",
+				"Submitted at 01-03-2022 19:59 UTC, before the deadline 01-03-2022 20:59 UTC",
+				"No scoring policy",
+				"Submitted after the final deadline",
+				"Retake policy summer applied",
+			},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if str := test.explanation.String(); str != strings.Join(test.lines, "\n") {
+				t.Fatalf("Invalid explanation:\n%s", str)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package scorer
+
+import (
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+const lateDay = time.Hour * 24
+
+type submission struct {
+	scored   *ScoredTask
+	task     *deadlines.Task
+	policy   deadlines.ScoringPolicy
+	deadline time.Time
+	pipeline *models.Pipeline
+	// explanation is filled by the scorer
+	explanation *Explanation
+}
+
+type lateDaysBudget struct {
+	total int
+	left  int
+}
+
+func newLateDaysBudget(total int) *lateDaysBudget {
+	return &lateDaysBudget{total, total}
+}
+
+// spend spends whole late days required to meet the submit time.
+// If the budget is not enough, the remaining days are spent and the deadline is shifted only partially.
+// Returns the number of spent days and the shifted deadline.
+func (b *lateDaysBudget) spend(deadline, submitTime time.Time) (int, time.Time) {
+	if b.left <= 0 || !submitTime.After(deadline) {
+		return 0, deadline
+	}
+
+	late := submitTime.Sub(deadline)
+	days := int((late + lateDay - 1) / lateDay)
+	if days > b.left {
+		days = b.left
+	}
+	b.left -= days
+
+	return days, deadline.Add(time.Duration(days) * lateDay)
+}
@@ -0,0 +1,118 @@
+package scorer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func TestLateDaysSpend(t *testing.T) {
+	deadline := time.Date(2022, time.March, 1, 23, 59, 0, 0, time.UTC)
+
+	for _, test := range []struct {
+		name     string
+		left     int
+		late     time.Duration
+		days     int
+		shiftDay int
+	}{
+		{name: "in time", left: 3, late: -time.Hour, days: 0, shiftDay: 0},
+		{name: "at deadline", left: 3, late: 0, days: 0, shiftDay: 0},
+		{name: "one minute", left: 3, late: time.Minute, days: 1, shiftDay: 1},
+		{name: "whole day", left: 3, late: lateDay, days: 1, shiftDay: 1},
+		{name: "partial second day", left: 3, late: lateDay + time.Hour, days: 2, shiftDay: 2},
+		{name: "not enough days", left: 1, late: 2*lateDay + time.Hour, days: 1, shiftDay: 1},
+		{name: "empty budget", left: 0, late: time.Hour, days: 0, shiftDay: 0},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			budget := &lateDaysBudget{total: 3, left: test.left}
+			days, shifted := budget.spend(deadline, deadline.Add(test.late))
+			if days != test.days {
+				t.Errorf("Spent %d days, expected %d", days, test.days)
+			}
+			if expected := deadline.Add(time.Duration(test.shiftDay) * lateDay); !shifted.Equal(expected) {
+				t.Errorf("Deadline is shifted to %s, expected %s", shifted, expected)
+			}
+			if budget.left != test.left-test.days {
+				t.Errorf("Budget has %d days left, expected %d", budget.left, test.left-test.days)
+			}
+		})
+	}
+}
+
+func TestLateDaysExhaustion(t *testing.T) {
+	deadline := time.Date(2022, time.March, 1, 23, 59, 0, 0, time.UTC)
+	budget := newLateDaysBudget(3)
+
+	for _, expected := range []int{2, 1, 0} {
+		if days, _ := budget.spend(deadline, deadline.Add(lateDay+time.Hour)); days != expected {
+			t.Fatalf("Spent %d days, expected %d", days, expected)
+		}
+	}
+	if budget.left != 0 {
+		t.Fatalf("Budget has %d days left", budget.left)
+	}
+}
+
+const lateDaysDeadlines = `
+scoring:
+  lateDays: 2
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: hw
+    weight: 1
+    policy: hard
+  defaultGroup: hw
+assignments:
+- title:    01-intro
+  start:    01-02-2022 00:00
+  deadline: 01-03-2022 23:59
+  tasks:
+    - task: first
+      score: 100
+    - task: second
+      score: 100
+    - task: third
+      score: 100
+`
+
+func TestLateDaysScoring(t *testing.T) {
+	scorer, db := newTestScorer(t, lateDaysDeadlines)
+	user := addTestUser(t, db, "ivanov", "Ivanov")
+
+	// Deadline is 01-03-2022 20:59 UTC, the budget is spent in the order of submissions, not tasks
+	addTestPipeline(t, db, "ivanov", "first", "03-03-2022 10:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "ivanov", "second", "02-03-2022 10:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "ivanov", "third", "01-03-2022 22:00", models.PipelineStatusFailed)
+
+	scores, err := scorer.CalcUserScores(user)
+	if err != nil {
+		t.Fatal("Failed to calc scores:", err)
+	}
+
+	expected := map[string]struct {
+		score    int
+		lateDays int
+	}{
+		// second was submitted first and took one day, one day left is not enough for two days late first
+		"first":  {0, 1},
+		"second": {100, 1},
+		// failed pipelines do not spend late days
+		"third": {0, 0},
+	}
+	for task, exp := range expected {
+		scored := findTask(scores, task)
+		if scored.Score != exp.score || scored.LateDays != exp.lateDays {
+			t.Errorf("Invalid task %s: score %d, late days %d, expected %d, %d", task, scored.Score, scored.LateDays, exp.score, exp.lateDays)
+		}
+		if scored.Explanation.LateDays != scored.LateDays {
+			t.Errorf("Explanation of %s has %d late days", task, scored.Explanation.LateDays)
+		}
+	}
+	if scores.LateDays == nil || scores.LateDays.Used != 2 || scores.LateDays.Left != 0 {
+		t.Errorf("Invalid late days: %+v", scores.LateDays)
+	}
+}
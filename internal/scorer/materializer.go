@@ -0,0 +1,273 @@
+package scorer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	lf "github.com/bigredeye/notmanytask/internal/logfield"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+const (
+	// materializeMargin covers changes committed concurrently with the previous update
+	materializeMargin = 10 * time.Second
+	// materializeBatchSize is the minimal number of changed users recalculated from all pipelines at once
+	materializeBatchSize = 16
+	// materializeBatchShare is the minimal share of the changed users in the group recalculated from all pipelines at once,
+	// loading all pipelines for a few users of a large group is slower than the per-user queries
+	materializeBatchShare = 0.25
+)
+
+// Materializer keeps user scores in the database and recalculates only the users
+// whose pipelines, flags, overrides, extensions or deadlines changed since the previous update.
+type Materializer struct {
+	scorer *Scorer
+	config *config.Config
+	logger *zap.Logger
+
+	ready         atomic.Bool
+	lastUpdate    time.Time
+	seenDeadlines map[string]*deadlines.Deadlines
+}
+
+func (s *Scorer) NewMaterializer(config *config.Config, logger *zap.Logger) *Materializer {
+	return &Materializer{
+		scorer:        s,
+		config:        config,
+		logger:        logger,
+		seenDeadlines: make(map[string]*deadlines.Deadlines),
+	}
+}
+
+func (m *Materializer) Run(ctx context.Context) {
+	interval := m.config.PullIntervals.Scores
+	if interval == nil {
+		return
+	}
+
+	tick := time.NewTicker(*interval)
+
+	for {
+		select {
+		case <-tick.C:
+			if err := m.update(); err != nil {
+				m.logger.Error("Failed to update scores", zap.Error(err))
+			}
+		case <-ctx.Done():
+			m.logger.Info("Stopping scores materializer")
+			return
+		}
+	}
+}
+
+func (m *Materializer) update() error {
+	started := time.Now()
+
+	changedProjects := make(map[string]bool)
+	changedLogins := make(map[string]bool)
+	if !m.lastUpdate.IsZero() {
+		projects, err := m.scorer.db.ListProjectsChangedSince(m.lastUpdate)
+		if err != nil {
+			return fmt.Errorf("failed to list changed projects: %w", err)
+		}
+		for _, project := range projects {
+			changedProjects[project] = true
+		}
+
+		logins, err := m.scorer.db.ListLoginsChangedSince(m.lastUpdate)
+		if err != nil {
+			return fmt.Errorf("failed to list changed users: %w", err)
+		}
+		for _, login := range logins {
+			changedLogins[login] = true
+		}
+	}
+
+	for _, group := range m.config.Groups {
+		currentDeadlines := m.scorer.deadlines.GroupDeadlines(group.Name)
+		if currentDeadlines == nil {
+			continue
+		}
+
+		users, err := m.scorer.db.ListGroupUsers(group.Name)
+		if err != nil {
+			return err
+		}
+
+		// Deadlines are replaced by the fetcher only if they were changed
+		full := m.lastUpdate.IsZero() || m.seenDeadlines[group.Name] != currentDeadlines
+		dirty := make(map[string]bool)
+		for _, user := range users {
+			if full || changedLogins[*user.GitlabLogin] || changedProjects[m.scorer.projects.MakeProjectName(user)] {
+				dirty[*user.GitlabLogin] = true
+			}
+		}
+
+		if err := m.materialize(group.Name, users, dirty); err != nil {
+			return err
+		}
+		m.seenDeadlines[group.Name] = currentDeadlines
+	}
+
+	m.lastUpdate = started.Add(-materializeMargin)
+	m.ready.Store(true)
+	return nil
+}
+
+func (m *Materializer) materialize(groupName string, users []*models.User, dirty map[string]bool) error {
+	var scores []*UserScores
+	if len(dirty) > materializeBatchSize && float64(len(dirty)) >= materializeBatchShare*float64(len(users)) {
+		standings, err := m.scorer.CalcScoreboardWithFilter(groupName, func(user *models.User) bool {
+			return dirty[*user.GitlabLogin]
+		}, time.Time{})
+		if err != nil {
+			return err
+		}
+		scores = standings.Users
+	} else {
+		for _, user := range users {
+			if !dirty[*user.GitlabLogin] {
+				continue
+			}
+			userScores, err := m.scorer.CalcUserScores(user)
+			if err != nil {
+				return err
+			}
+			scores = append(scores, userScores)
+		}
+	}
+
+	for _, userScores := range scores {
+		if err := m.save(groupName, userScores); err != nil {
+			return fmt.Errorf("failed to save scores: %w", err)
+		}
+	}
+	if len(scores) > 0 {
+		m.logger.Info("Updated scores", zap.String("group", groupName), zap.Int("users", len(scores)))
+	}
+	return nil
+}
+
+func (m *Materializer) save(groupName string, scores *UserScores) error {
+	encoded, err := json.Marshal(scores)
+	if err != nil {
+		return err
+	}
+
+	return m.scorer.db.SaveMaterializedScores(&models.MaterializedScores{
+		GitlabLogin: scores.User.GitlabLogin,
+		GroupName:   groupName,
+		Scores:      encoded,
+	})
+}
+
+// Refresh recalculates the user scores right away, e.g. when the pipeline has just finished.
+func (m *Materializer) Refresh(user *models.User) (*UserScores, error) {
+	scores, err := m.scorer.CalcUserScores(user)
+	if err != nil {
+		return nil, err
+	}
+	if m.config.PullIntervals.Scores != nil {
+		if err := m.save(user.GroupName, scores); err != nil {
+			return nil, fmt.Errorf("failed to save scores: %w", err)
+		}
+	}
+	return scores, nil
+}
+
+// decodeScores moves the decoded deadlines to the location of the group deadlines,
+// dates keep only the offset through JSON and are labelled by it otherwise.
+func decodeScores(row *models.MaterializedScores, loc *time.Location) (*UserScores, error) {
+	scores := &UserScores{}
+	if err := json.Unmarshal(row.Scores, scores); err != nil {
+		return nil, err
+	}
+	for i := range scores.Groups {
+		group := &scores.Groups[i]
+		group.Deadline.Time = group.Deadline.In(loc)
+		for j := range group.Tasks {
+			if deadline := group.Tasks[j].Deadline; deadline != nil {
+				deadline.Time = deadline.In(loc)
+			}
+		}
+	}
+	return scores, nil
+}
+
+// UserScores returns the materialized scores of the user.
+// Scores are calculated directly if materialization is disabled or the user is not materialized yet.
+func (m *Materializer) UserScores(user *models.User) (*UserScores, error) {
+	if !m.ready.Load() {
+		return m.scorer.CalcUserScores(user)
+	}
+
+	row, err := m.scorer.db.FindMaterializedScores(*user.GitlabLogin)
+	if err != nil || row.GroupName != user.GroupName {
+		return m.scorer.CalcUserScores(user)
+	}
+
+	scores, err := decodeScores(row, m.scorer.deadlines.GroupDeadlines(user.GroupName).Location())
+	if err != nil {
+		m.logger.Warn("Failed to decode materialized scores", lf.GitlabLogin(*user.GitlabLogin), zap.Error(err))
+		return m.scorer.CalcUserScores(user)
+	}
+	return scores, nil
+}
+
+// Standings returns the materialized standings of the group, see UserScores.
+func (m *Materializer) Standings(groupName string, filter UserFilter) (*Standings, error) {
+	if !m.ready.Load() {
+		return m.scorer.CalcScoreboardWithFilter(groupName, filter, time.Time{})
+	}
+
+	currentDeadlines := m.scorer.deadlines.GroupDeadlines(groupName)
+	if currentDeadlines == nil {
+		return nil, fmt.Errorf("no deadlines found")
+	}
+
+	users, err := m.scorer.db.ListGroupUsers(groupName)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := m.scorer.db.ListMaterializedScores(groupName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list materialized scores: %w", err)
+	}
+	byLogin := make(map[string]*models.MaterializedScores, len(rows))
+	for i := range rows {
+		byLogin[rows[i].GitlabLogin] = &rows[i]
+	}
+
+	scores := make([]*UserScores, 0, len(users))
+	for _, user := range users {
+		if filter != nil && !filter(user) {
+			continue
+		}
+
+		var userScores *UserScores
+		if row, found := byLogin[*user.GitlabLogin]; found {
+			userScores, err = decodeScores(row, currentDeadlines.Location())
+			if err != nil {
+				m.logger.Warn("Failed to decode materialized scores", lf.GitlabLogin(*user.GitlabLogin), zap.Error(err))
+			}
+		}
+		if userScores == nil {
+			userScores, err = m.scorer.CalcUserScores(user)
+			if err != nil {
+				return nil, err
+			}
+		}
+		scores = append(scores, userScores)
+	}
+	sortScores(scores)
+
+	return &Standings{copyDeadlines(currentDeadlines), scores}, nil
+}
@@ -0,0 +1,221 @@
+package scorer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func newTestMaterializer(scorer *Scorer) *Materializer {
+	interval := time.Minute
+	conf := &config.Config{Groups: config.GroupsConfig{{Name: testGroup}}}
+	conf.PullIntervals.Scores = &interval
+	return scorer.NewMaterializer(conf, zap.NewNop())
+}
+
+func materializedAt(t *testing.T, db database.Store, login string) time.Time {
+	t.Helper()
+	row, err := db.FindMaterializedScores(login)
+	if err != nil {
+		t.Fatalf("Scores of %s are not materialized: %v", login, err)
+	}
+	return row.UpdatedAt
+}
+
+// skipMargin pretends the previous update was long enough ago, otherwise the recent changes are recalculated twice.
+func skipMargin(m *Materializer) {
+	m.lastUpdate = time.Now()
+	time.Sleep(time.Millisecond)
+}
+
+func TestMaterializerUpdatesChangedUsers(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	armstrong := addTestUser(t, db, "armstrong", "Armstrong")
+	addTestUser(t, db, "aldrin", "Aldrin")
+	addTestPipeline(t, db, "armstrong", "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+
+	m := newTestMaterializer(scorer)
+	if err := m.update(); err != nil {
+		t.Fatal("Failed to materialize scores:", err)
+	}
+	armstrongAt, aldrinAt := materializedAt(t, db, "armstrong"), materializedAt(t, db, "aldrin")
+
+	scores, err := m.UserScores(armstrong)
+	if err != nil || scores.Score != 100 {
+		t.Fatalf("Invalid materialized scores: %+v, %v", scores, err)
+	}
+
+	// Unchanged deadlines and users are not recalculated
+	skipMargin(m)
+	if err = m.update(); err != nil {
+		t.Fatal("Failed to materialize scores:", err)
+	}
+	if !materializedAt(t, db, "armstrong").Equal(armstrongAt) || !materializedAt(t, db, "aldrin").Equal(aldrinAt) {
+		t.Fatal("Unchanged users were recalculated")
+	}
+
+	skipMargin(m)
+	addTestPipeline(t, db, "aldrin", "rewrite-in-go", "20-07-1969 11:00", models.PipelineStatusSuccess)
+	if err = db.AddOverride("armstrong", "rewrite-in-rust", 10, models.PipelineStatusSuccess, nil); err != nil {
+		t.Fatal("Failed to add override:", err)
+	}
+	if err = m.update(); err != nil {
+		t.Fatal("Failed to materialize scores:", err)
+	}
+	if materializedAt(t, db, "armstrong").Equal(armstrongAt) || materializedAt(t, db, "aldrin").Equal(aldrinAt) {
+		t.Fatal("Changed users were not recalculated")
+	}
+
+	standings, err := m.Standings(testGroup, nil)
+	if err != nil {
+		t.Fatal("Failed to load standings:", err)
+	}
+	if len(standings.Users) != 2 || standings.Users[0].Score != 110 || standings.Users[1].Score != 50 {
+		t.Fatalf("Invalid materialized standings: %+v", standings.Users)
+	}
+}
+
+func TestMaterializerFallsBackToScorer(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	armstrong := addTestUser(t, db, "armstrong", "Armstrong")
+	addTestPipeline(t, db, "armstrong", "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+
+	// Scores are calculated on the fly until the first update
+	m := newTestMaterializer(scorer)
+	scores, err := m.UserScores(armstrong)
+	if err != nil || scores.Score != 100 {
+		t.Fatalf("Invalid scores: %+v, %v", scores, err)
+	}
+	if _, err = db.FindMaterializedScores("armstrong"); err == nil {
+		t.Fatal("Scores were materialized before the first update")
+	}
+
+	refreshed, err := m.Refresh(armstrong)
+	if err != nil || refreshed.Score != 100 {
+		t.Fatalf("Invalid refreshed scores: %+v, %v", refreshed, err)
+	}
+	materializedAt(t, db, "armstrong")
+}
+
+// countingStore counts full pipelines scans
+type countingStore struct {
+	database.Store
+	scans int
+}
+
+func (s *countingStore) ListAllPipelines() ([]models.Pipeline, error) {
+	s.scans++
+	return s.Store.ListAllPipelines()
+}
+
+func TestMaterializerBatchesLargeChanges(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	store := &countingStore{Store: db}
+	scorer.db = store
+
+	const usersCount = 80
+	logins := make([]string, usersCount)
+	for i := range logins {
+		logins[i] = fmt.Sprintf("astronaut%d", i)
+		addTestUser(t, db, logins[i], fmt.Sprintf("Astronaut%d", i))
+	}
+
+	m := newTestMaterializer(scorer)
+	if err := m.update(); err != nil {
+		t.Fatal("Failed to materialize scores:", err)
+	}
+	if store.scans != 1 {
+		t.Fatalf("Initial update scanned all pipelines %d times", store.scans)
+	}
+
+	for _, test := range []struct {
+		changed int
+		scans   int
+	}{
+		// Above the batch size, but a small share of the group
+		{changed: materializeBatchSize + 1, scans: 1},
+		{changed: usersCount / 2, scans: 2},
+	} {
+		skipMargin(m)
+		for _, login := range logins[:test.changed] {
+			addTestPipeline(t, db, login, "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+		}
+		if err := m.update(); err != nil {
+			t.Fatal("Failed to materialize scores:", err)
+		}
+		if store.scans != test.scans {
+			t.Fatalf("%d changed users: all pipelines were scanned %d times, expected %d", test.changed, store.scans, test.scans)
+		}
+		for _, login := range logins[:test.changed] {
+			user, err := db.FindUserByGitlabLogin(login)
+			if err != nil {
+				t.Fatal("Failed to find user:", err)
+			}
+			scores, err := m.UserScores(user)
+			if err != nil || scores.Score == 0 {
+				t.Fatalf("Scores of %s were not updated: %+v, %v", login, scores, err)
+			}
+		}
+	}
+}
+
+const yekaterinburgDeadlines = `
+timezone: Asia/Yekaterinburg
+scoring:
+  policies:
+  - name: hard
+    kind: hard
+  groups:
+  - name: hw
+    weight: 1
+    policy: hard
+  defaultGroup: hw
+assignments:
+- title:    01-intro
+  start:    01-02-2022 00:00
+  deadline: 01-03-2022 23:59
+  tasks:
+    - task: first
+      score: 100
+    - task: second
+      score: 100
+`
+
+func TestMaterializerKeepsTimezone(t *testing.T) {
+	scorer, db := newTestScorer(t, yekaterinburgDeadlines)
+	user := addTestUser(t, db, "ivanov", "Ivanov")
+	extended := time.Date(2022, time.March, 5, 18, 59, 0, 0, time.UTC)
+	if err := db.AddExtension("ivanov", "second", extended, "illness"); err != nil {
+		t.Fatal("Failed to add extension:", err)
+	}
+
+	expected, err := scorer.CalcUserScores(user)
+	if err != nil {
+		t.Fatal("Failed to calc scores:", err)
+	}
+
+	m := newTestMaterializer(scorer)
+	if err = m.update(); err != nil {
+		t.Fatal("Failed to materialize scores:", err)
+	}
+	materializedAt(t, db, "ivanov")
+
+	scores, err := m.UserScores(user)
+	if err != nil {
+		t.Fatal("Failed to load materialized scores:", err)
+	}
+	group, expectedGroup := scores.Groups[0], expected.Groups[0]
+	if !group.Deadline.Equal(expectedGroup.Deadline.Time) || group.Deadline.String() != "01-03-2022 23:59 +05" {
+		t.Fatalf("Invalid materialized deadline %s, expected %s", group.Deadline.String(), expectedGroup.Deadline.String())
+	}
+	extension := findTask(scores, "second").Deadline
+	if extension == nil || !extension.Equal(extended) || extension.String() != "05-03-2022 23:59 +05" {
+		t.Fatalf("Invalid materialized extension %v, expected %s", extension, extended)
+	}
+}
@@ -0,0 +1,14 @@
+package scorer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var scoreboardDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: "notmanytask",
+	Subsystem: "scorer",
+	Name:      "scoreboard_duration_seconds",
+	Help:      "Time spent calculating the group scoreboard.",
+	Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+})
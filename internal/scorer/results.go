@@ -1,6 +1,8 @@
 package scorer
 
 import (
+	"fmt"
+
 	"github.com/bigredeye/notmanytask/internal/deadlines"
 	"github.com/bigredeye/notmanytask/internal/models"
 )
@@ -42,6 +44,18 @@ type ScoredTask struct {
 	Score      int
 	MaxScore   int
 	Overridden bool
+	// Deadline is set only if it differs from the task group deadline
+	Deadline *deadlines.Date `json:",omitempty"`
+	LateDays int             `json:",omitempty"`
+	// Retaken is set if the score was changed by the retake policy
+	Retaken bool `json:",omitempty"`
+	// Dropped is set if the score is not counted by the scoring group aggregation
+	Dropped bool `json:",omitempty"`
+	// Tests counts are set only if the grader reported them
+	TestsPassed int `json:",omitempty"`
+	TestsTotal  int `json:",omitempty"`
+	// Explanation is set if the task was submitted or overridden
+	Explanation *Explanation `json:",omitempty"`
 
 	TaskUrl     string
 	PipelineUrl string
@@ -53,6 +67,8 @@ type ScoredTaskGroup struct {
 	PrettyTitle string
 	Deadline    deadlines.Date
 	Tasks       []ScoredTask
+	// Hidden is set for the retake-only task groups unavailable to the student
+	Hidden bool `json:",omitempty"`
 
 	Score    int
 	MaxScore int
@@ -69,16 +85,76 @@ func (u User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+type LateDays struct {
+	Used int
+	Left int
+}
+
 type UserScores struct {
 	Groups    []ScoredTaskGroup
 	Score     int
 	MaxScore  int
 	FinalMark float64
+	// FinalGrade is set only if the course has grading configured
+	FinalGrade *float64 `json:",omitempty"`
+	// LateDays is set only if the course has late days budget
+	LateDays *LateDays `json:",omitempty"`
+	// RetakePolicy is the name of the student retake policy, empty if there is no retake
+	RetakePolicy string `json:",omitempty"`
+	// UnmetRequirements lists required tasks and scoring group minimums the student has not reached yet
+	UnmetRequirements []UnmetRequirement `json:",omitempty"`
 
 	User User
 }
 
+// UnmetRequirement is either the unsolved required task or the scoring group with too low score.
+type UnmetRequirement struct {
+	Task         string `json:",omitempty"`
+	ScoringGroup string `json:",omitempty"`
+	Score        int
+	MinScore     int
+}
+
+func (r UnmetRequirement) String() string {
+	if r.Task != "" {
+		return fmt.Sprintf("required task %s is not solved", r.Task)
+	}
+	return fmt.Sprintf("%s score %d is less than required %d", r.ScoringGroup, r.Score, r.MinScore)
+}
+
+// AtRisk reports whether the student fails the course unless the requirements are met.
+func (s *UserScores) AtRisk() bool {
+	return len(s.UnmetRequirements) > 0
+}
+
 type Standings struct {
 	Deadlines *deadlines.Deadlines
 	Users     []*UserScores
 }
+
+type RetakeSummary struct {
+	Policy   *deadlines.RetakePolicy
+	Students int
+}
+
+// RetakeBreakdown counts students per retake policy in the order of policies in deadlines.
+func (s *Standings) RetakeBreakdown() []RetakeSummary {
+	students := make(map[string]int)
+	for _, user := range s.Users {
+		if user.RetakePolicy != "" {
+			students[user.RetakePolicy]++
+		}
+	}
+
+	summaries := make([]RetakeSummary, 0)
+	for i := range s.Deadlines.Scoring.RetakePolicies {
+		policy := &s.Deadlines.Scoring.RetakePolicies[i]
+		summaries = append(summaries, RetakeSummary{policy, students[policy.Name]})
+		delete(students, policy.Name)
+	}
+	// Implicit default policy
+	if count, found := students[deadlines.DefaultRetakePolicy]; found {
+		summaries = append(summaries, RetakeSummary{s.Deadlines.GetRetakePolicy(deadlines.DefaultRetakePolicy), count})
+	}
+	return summaries
+}
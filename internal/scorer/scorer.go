@@ -2,15 +2,18 @@ package scorer
 
 import (
 	"fmt"
+	"math"
 	"path"
 	"regexp"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/bigredeye/notmanytask/internal/database"
 	"github.com/bigredeye/notmanytask/internal/deadlines"
 	"github.com/bigredeye/notmanytask/internal/models"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 type ProjectNameFactory interface {
@@ -23,11 +26,11 @@ type ProjectNameFactory interface {
 
 type Scorer struct {
 	deadlines *deadlines.Fetcher
-	db        *database.DataBase
+	db        database.Store
 	projects  ProjectNameFactory
 }
 
-func NewScorer(db *database.DataBase, deadlines *deadlines.Fetcher, projects ProjectNameFactory) *Scorer {
+func NewScorer(db database.Store, deadlines *deadlines.Fetcher, projects ProjectNameFactory) *Scorer {
 	return &Scorer{deadlines, db, projects}
 }
 
@@ -60,8 +63,21 @@ func classifyPipelineStatus(status models.PipelineStatus) taskStatus {
 	}
 }
 
+func pipelineFinished(pipeline *models.Pipeline) bool {
+	switch classifyPipelineStatus(pipeline.Status) {
+	case taskStatusBanned, taskStatusFailed, taskStatusSuccess:
+		return true
+	default:
+		return false
+	}
+}
+
+// pipelineLess prefers pipelines with better status, then with more partial credit, then earlier ones.
 func pipelineLess(left *models.Pipeline, right *models.Pipeline) bool {
 	if classifyPipelineStatus(left.Status) == classifyPipelineStatus(right.Status) {
+		if left.ScoreFraction() != right.ScoreFraction() {
+			return left.ScoreFraction() > right.ScoreFraction()
+		}
 		return left.StartedAt.Before(right.StartedAt)
 	}
 
@@ -69,7 +85,8 @@ func pipelineLess(left *models.Pipeline, right *models.Pipeline) bool {
 }
 
 // TODO(BigRedEye): Unify submits?
-type pipelinesMap map[string]*models.Pipeline
+// pipelinesMap keeps all the task pipelines ordered by pipelineLess, see selectPipeline
+type pipelinesMap map[string][]*models.Pipeline
 type flagsMap map[string]*models.Flag
 
 type pipelinesProvider = func(project string) (pipelines []models.Pipeline, err error)
@@ -84,15 +101,50 @@ func (s Scorer) loadUserPipelines(user *models.User, provider pipelinesProvider)
 	pipelinesMap := make(pipelinesMap)
 	for i := range pipelines {
 		pipeline := &pipelines[i]
-		prev, found := pipelinesMap[pipeline.Task]
-		if !found || pipelineLess(pipeline, prev) {
-			prev = pipeline
-		}
-		pipelinesMap[pipeline.Task] = prev
+		pipelinesMap[pipeline.Task] = append(pipelinesMap[pipeline.Task], pipeline)
+	}
+	for _, candidates := range pipelinesMap {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return pipelineLess(candidates[i], candidates[j])
+		})
 	}
 	return pipelinesMap, nil
 }
 
+// selectPipeline picks one of the task pipelines according to the selection strategy of the scoring group.
+// Candidates are ordered by pipelineLess, the first one is selected by default.
+func selectPipeline(candidates []*models.Pipeline, strategy string, score func(pipeline *models.Pipeline) int) *models.Pipeline {
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	selected := candidates[0]
+	switch strategy {
+	case deadlines.SelectionLatest:
+		// The score should not drop to zero while the new submission is being checked
+		var latest *models.Pipeline
+		for _, pipeline := range candidates {
+			if !pipelineFinished(pipeline) {
+				continue
+			}
+			if latest == nil || pipeline.StartedAt.After(latest.StartedAt) {
+				latest = pipeline
+			}
+		}
+		if latest != nil {
+			selected = latest
+		}
+	case deadlines.SelectionBest:
+		best := score(selected)
+		for _, pipeline := range candidates[1:] {
+			if current := score(pipeline); current > best {
+				selected, best = pipeline, current
+			}
+		}
+	}
+	return selected
+}
+
 func (s Scorer) loadUserFlags(user *models.User, provider flagsProvider) (flagsMap, error) {
 	flags, err := provider(*user.GitlabLogin)
 	if err != nil {
@@ -121,10 +173,16 @@ func copyDeadlines(src *deadlines.Deadlines) *deadlines.Deadlines {
 type UserFilter = func(user *models.User) bool
 
 func (s Scorer) CalcScoreboard(groupName string) (*Standings, error) {
-	return s.CalcScoreboardWithFilter(groupName, nil)
+	return s.CalcScoreboardWithFilter(groupName, nil, time.Time{})
 }
 
-func (s Scorer) CalcScoreboardWithFilter(groupName string, filter UserFilter) (*Standings, error) {
+// CalcScoreboardWithFilter calculates standings as they were at asOf moment, zero asOf means now.
+// Pipelines, flags, extensions and users created later are ignored, overrides are restored from their history.
+// Current deadlines are used.
+func (s Scorer) CalcScoreboardWithFilter(groupName string, filter UserFilter, asOf time.Time) (*Standings, error) {
+	timer := prometheus.NewTimer(scoreboardDuration)
+	defer timer.ObserveDuration()
+
 	currentDeadlines := s.deadlines.GroupDeadlines(groupName)
 	if currentDeadlines == nil {
 		return nil, fmt.Errorf("no deadlines found")
@@ -135,34 +193,50 @@ func (s Scorer) CalcScoreboardWithFilter(groupName string, filter UserFilter) (*
 		return nil, err
 	}
 
-	if filter != nil {
+	if filter != nil || !asOf.IsZero() {
 		allUsers := users
 		users = make([]*models.User, 0, len(allUsers))
 		for _, user := range allUsers {
-			if filter(user) {
+			if (filter == nil || filter(user)) && !isCreatedAfter(user.CreatedAt, asOf) {
 				users = append(users, user)
 			}
 		}
 	}
 
-	pipelines, err := s.makeCachedPipelinesProvider()
+	pipelines, err := s.makeCachedPipelinesProvider(asOf)
 	if err != nil {
 		return nil, err
 	}
 
-	flags, err := s.makeCachedFlagsProvider()
+	flags, err := s.makeCachedFlagsProvider(asOf)
 	if err != nil {
 		return nil, err
 	}
 
-	overrides, err := s.db.ListOverrides()
+	var overrides []models.OverriddenScore
+	if asOf.IsZero() {
+		overrides, err = s.db.ListOverrides()
+	} else {
+		overrides, err = s.db.ListOverridesAt(asOf)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to list all overrides: %w", err)
 	}
 
+	allExtensions, err := s.db.ListExtensions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list all extensions: %w", err)
+	}
+	extensions := make([]models.Extension, 0, len(allExtensions))
+	for _, extension := range allExtensions {
+		if !isCreatedAfter(extension.CreatedAt, asOf) {
+			extensions = append(extensions, extension)
+		}
+	}
+
 	scores := make([]*UserScores, len(users))
 	for i, user := range users {
-		userScores, err := s.calcUserScoresImpl(currentDeadlines, user, pipelines, flags, overrides)
+		userScores, err := s.calcUserScoresImpl(currentDeadlines, user, pipelines, flags, overrides, extensions)
 		if err != nil {
 			return nil, err
 		}
@@ -170,17 +244,26 @@ func (s Scorer) CalcScoreboardWithFilter(groupName string, filter UserFilter) (*
 		scores[i] = userScores
 	}
 
+	sortScores(scores)
+
+	return &Standings{copyDeadlines(currentDeadlines), scores}, nil
+}
+
+func sortScores(scores []*UserScores) {
 	sort.Slice(scores, func(i, j int) bool {
 		if scores[i].FinalMark != scores[j].FinalMark {
 			return scores[i].FinalMark > scores[j].FinalMark
 		}
 		return scores[i].User.FullName() < scores[j].User.FullName()
 	})
+}
 
-	return &Standings{copyDeadlines(currentDeadlines), scores}, nil
+// isCreatedAfter reports whether the object did not exist at asOf moment, zero asOf means now.
+func isCreatedAfter(createdAt time.Time, asOf time.Time) bool {
+	return !asOf.IsZero() && createdAt.After(asOf)
 }
 
-func (s Scorer) makeCachedPipelinesProvider() (pipelinesProvider, error) {
+func (s Scorer) makeCachedPipelinesProvider(asOf time.Time) (pipelinesProvider, error) {
 	pipelines, err := s.db.ListAllPipelines()
 	if err != nil {
 		return nil, err
@@ -188,6 +271,9 @@ func (s Scorer) makeCachedPipelinesProvider() (pipelinesProvider, error) {
 
 	pipelinesMap := make(map[string][]models.Pipeline)
 	for _, pipeline := range pipelines {
+		if isCreatedAfter(pipeline.StartedAt, asOf) {
+			continue
+		}
 		prev, found := pipelinesMap[pipeline.Project]
 		if !found {
 			prev = make([]models.Pipeline, 0, 1)
@@ -201,7 +287,7 @@ func (s Scorer) makeCachedPipelinesProvider() (pipelinesProvider, error) {
 	}, nil
 }
 
-func (s Scorer) makeCachedFlagsProvider() (flagsProvider, error) {
+func (s Scorer) makeCachedFlagsProvider(asOf time.Time) (flagsProvider, error) {
 	flags, err := s.db.ListSubmittedFlags()
 	if err != nil {
 		return nil, err
@@ -209,6 +295,13 @@ func (s Scorer) makeCachedFlagsProvider() (flagsProvider, error) {
 
 	flagsMap := make(map[string][]models.Flag)
 	for _, flag := range flags {
+		submittedAt := flag.CreatedAt
+		if flag.SubmittedAt != nil {
+			submittedAt = *flag.SubmittedAt
+		}
+		if isCreatedAfter(submittedAt, asOf) {
+			continue
+		}
 		prev, found := flagsMap[*flag.GitlabLogin]
 		if !found {
 			prev = make([]models.Flag, 0, 1)
@@ -233,7 +326,12 @@ func (s Scorer) CalcUserScores(user *models.User) (*UserScores, error) {
 		return nil, fmt.Errorf("failed to list user overrides: %w", err)
 	}
 
-	return s.calcUserScoresImpl(currentDeadlines, user, s.db.ListProjectPipelines, s.db.ListUserFlags, overrides)
+	extensions, err := s.db.ListUserExtensions(*user.GitlabLogin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user extensions: %w", err)
+	}
+
+	return s.calcUserScoresImpl(currentDeadlines, user, s.db.ListProjectPipelines, s.db.ListUserFlags, overrides, extensions)
 }
 
 type overrideKey struct {
@@ -252,7 +350,36 @@ func parseOverrides(overrides []models.OverriddenScore) (result map[overrideKey]
 	return
 }
 
-func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *models.User, pipelinesP pipelinesProvider, flagsP flagsProvider, rawOverrides []models.OverriddenScore) (*UserScores, error) {
+func parseExtensions(extensions []models.Extension) (result map[overrideKey]*models.Extension) {
+	result = make(map[overrideKey]*models.Extension)
+	for i := range extensions {
+		result[overrideKey{
+			login: extensions[i].GitlabLogin,
+			task:  extensions[i].Task,
+		}] = &extensions[i]
+	}
+	return
+}
+
+// findDeadline returns the deadline of the task for the given user.
+// Extensions granted for the task take precedence over the ones granted for the whole task group.
+func findDeadline(
+	currentDeadlines *deadlines.Deadlines,
+	extensions map[overrideKey]*models.Extension,
+	login string,
+	task *deadlines.Task,
+	group *deadlines.TaskGroup,
+) time.Time {
+	if extension, found := extensions[overrideKey{login: login, task: task.Task}]; found {
+		return extension.Deadline
+	}
+	if extension, found := extensions[overrideKey{login: login, task: group.Title}]; found {
+		return extension.Deadline
+	}
+	return currentDeadlines.GetTaskDeadline(group, task).Time
+}
+
+func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *models.User, pipelinesP pipelinesProvider, flagsP flagsProvider, rawOverrides []models.OverriddenScore, rawExtensions []models.Extension) (*UserScores, error) {
 	pipelinesMap, err := s.loadUserPipelines(user, pipelinesP)
 	if err != nil {
 		return nil, err
@@ -264,6 +391,8 @@ func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *
 	}
 
 	overrides := parseOverrides(rawOverrides)
+	extensions := parseExtensions(rawExtensions)
+	retake := currentDeadlines.GetRetakePolicy(user.RetakePolicy)
 
 	scores := &UserScores{
 		Groups:    make([]ScoredTaskGroup, 0),
@@ -277,16 +406,32 @@ func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *
 			GitlabProject: s.projects.MakeProjectName(user),
 		},
 	}
+	if retake != nil {
+		scores.RetakePolicy = retake.Name
+	}
+
+	// Submissions are scored after all the tasks are collected:
+	// late days are spent in the chronological order of submissions.
+	submissions := make([]*submission, 0)
+	scoringGroups := make([][]*deadlines.ScoringGroup, len(currentDeadlines.Assignments))
 
-	for _, group := range currentDeadlines.Assignments {
+	for groupIdx, group := range currentDeadlines.Assignments {
 		tasks := make([]ScoredTask, len(group.Tasks))
-		totalScore := 0
 		maxTotalScore := 0
+		available := currentDeadlines.IsTaskGroupAvailable(&group, retake)
+
+		scores.Groups = append(scores.Groups, ScoredTaskGroup{
+			Title:       group.Title,
+			PrettyTitle: prettifyTitle(group.Title),
+			Deadline:    group.Deadline,
+			Tasks:       tasks,
+			Hidden:      !available,
+		})
+		scoringGroups[groupIdx] = make([]*deadlines.ScoringGroup, len(group.Tasks))
 
-		scoringGroup := currentDeadlines.GetScoringGroup(&group)
-		policy := currentDeadlines.GetScoringPolicy(&group)
+		for i := range group.Tasks {
+			task := &group.Tasks[i]
 
-		for i, task := range group.Tasks {
 			tasks[i] = ScoredTask{
 				Task:      task.Task,
 				ShortName: makeShortTaskName(task.Task),
@@ -295,25 +440,53 @@ func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *
 				MaxScore:  task.Score,
 				TaskUrl:   s.projects.MakeTaskURL(task.Task),
 			}
+			if !available {
+				continue
+			}
+			scoringGroups[groupIdx][i] = currentDeadlines.GetTaskScoringGroup(&group, task)
 			maxTotalScore += tasks[i].MaxScore
 
+			deadline := findDeadline(currentDeadlines, extensions, *user.GitlabLogin, task, &group)
+			if retake != nil && retake.Kind == deadlines.RetakeKindDeadline && retake.Deadline != nil && retake.Deadline.After(deadline) {
+				deadline = retake.Deadline.Time
+			}
+			if !deadline.Equal(group.Deadline.Time) {
+				// Extensions are stored without time zone, they are shown in the time zone of the deadlines
+				tasks[i].Deadline = &deadlines.Date{Time: deadline.In(currentDeadlines.Location())}
+			}
+			policy := currentDeadlines.GetTaskScoringPolicy(&group, task)
+			explanation := &Explanation{
+				Source: ExplanationSourcePipeline,
+				Policy: currentDeadlines.GetTaskScoringPolicyName(&group, task),
+			}
+
+			var pipeline *models.Pipeline
 			flag, found := flagsMap[task.Task]
 			if found {
 				tasks[i].Status = TaskStatusSuccess
+				explanation.Source = ExplanationSourceFlag
 
 				// FIXME(BigRedEye): I just want to sleep
 				// Do not try to mimic pipelines
-				tasks[i].Score = s.scorePipeline(policy, currentDeadlines, user, &task, &group, &models.Pipeline{
+				pipeline = &models.Pipeline{
 					StartedAt: flag.CreatedAt,
 					Status:    models.PipelineStatusSuccess,
-				})
+				}
 			} else if !task.Crashme {
-				pipeline, found := pipelinesMap[task.Task]
-				if found {
+				strategy := ""
+				if scoringGroup := scoringGroups[groupIdx][i]; scoringGroup != nil {
+					strategy = scoringGroup.Selection
+				}
+				// Late days are not taken into account while comparing the candidates
+				pipeline = selectPipeline(pipelinesMap[task.Task], strategy, func(pipeline *models.Pipeline) int {
+					return s.scorePipeline(policy, currentDeadlines, retake, task, deadline, deadline, pipeline, &Explanation{})
+				})
+				if pipeline != nil {
 					tasks[i].Status = ClassifyPipelineStatus(pipeline.Status)
-					tasks[i].Score = s.scorePipeline(policy, currentDeadlines, user, &task, &group, pipeline)
 					tasks[i].PipelineUrl = s.projects.MakePipelineURL(user, pipeline)
 					tasks[i].BranchUrl = s.projects.MakeBranchURL(user, pipeline)
+					tasks[i].TestsPassed = pipeline.TestsPassed
+					tasks[i].TestsTotal = pipeline.TestsTotal
 				}
 			}
 
@@ -322,23 +495,113 @@ func (s Scorer) calcUserScoresImpl(currentDeadlines *deadlines.Deadlines, user *
 				tasks[i].Score = override.Score
 				tasks[i].Status = ClassifyPipelineStatus(override.Status)
 				tasks[i].Overridden = true
+				tasks[i].Explanation = &Explanation{
+					Source: ExplanationSourceOverride,
+					Status: override.Status,
+				}
+			} else if pipeline != nil {
+				submissions = append(submissions, &submission{
+					scored:      &tasks[i],
+					task:        task,
+					policy:      policy,
+					deadline:    deadline,
+					pipeline:    pipeline,
+					explanation: explanation,
+				})
 			}
-
-			totalScore += tasks[i].Score
 		}
 
-		scores.Groups = append(scores.Groups, ScoredTaskGroup{
-			Title:       group.Title,
-			PrettyTitle: prettifyTitle(group.Title),
-			Deadline:    group.Deadline,
-			Score:       totalScore,
-			MaxScore:    maxTotalScore,
-			Tasks:       tasks,
-		})
-		scores.Score += totalScore
+		scores.Groups[groupIdx].MaxScore = maxTotalScore
 		scores.MaxScore += maxTotalScore
-		if scoringGroup != nil && scoringGroup.MaxScore > 0 {
-			scores.FinalMark += scoringGroup.Weight * float64(totalScore) / float64(scoringGroup.MaxScore)
+	}
+
+	budget := newLateDaysBudget(currentDeadlines.Scoring.LateDays)
+	sort.SliceStable(submissions, func(i, j int) bool {
+		return submissions[i].pipeline.StartedAt.Before(submissions[j].pipeline.StartedAt)
+	})
+	for _, sub := range submissions {
+		deadline := sub.deadline
+		if sub.pipeline.Status == models.PipelineStatusSuccess && !retake.IgnoresDeadlines() && !s.isAfterFinalDeadline(currentDeadlines, sub.deadline, sub.pipeline) {
+			sub.scored.LateDays, deadline = budget.spend(sub.deadline, sub.pipeline.StartedAt)
+		}
+		sub.scored.Score = s.scorePipeline(sub.policy, currentDeadlines, retake, sub.task, sub.deadline, deadline, sub.pipeline, sub.explanation)
+		sub.scored.Retaken = sub.explanation.Retake != ""
+		sub.explanation.LateDays = sub.scored.LateDays
+		sub.scored.Explanation = sub.explanation
+	}
+	if budget.total > 0 {
+		scores.LateDays = &LateDays{
+			Used: budget.total - budget.left,
+			Left: budget.left,
+		}
+	}
+
+	groupTasks := make(map[string][]*ScoredTask)
+	for i := range scores.Groups {
+		for j := range scores.Groups[i].Tasks {
+			if scoringGroup := scoringGroups[i][j]; scoringGroup != nil {
+				groupTasks[scoringGroup.Name] = append(groupTasks[scoringGroup.Name], &scores.Groups[i].Tasks[j])
+			}
+		}
+	}
+
+	marks := make(map[string]deadlines.GroupMark)
+	for i := range currentDeadlines.Scoring.Groups {
+		scoringGroup := &currentDeadlines.Scoring.Groups[i]
+		tasks := groupTasks[scoringGroup.Name]
+
+		taskScores := make([]int, len(tasks))
+		for j, task := range tasks {
+			taskScores[j] = task.Score
+		}
+		total, counted := scoringGroup.Aggregate(taskScores)
+		for j, task := range tasks {
+			task.Dropped = !counted[j]
+		}
+
+		if scoringGroup.MaxScore > 0 {
+			mark := scoringGroup.Weight * float64(total) / float64(scoringGroup.MaxScore)
+			scores.FinalMark += mark
+			marks[scoringGroup.Name] = deadlines.GroupMark{
+				Mark:     mark,
+				Score:    total,
+				MaxScore: scoringGroup.MaxScore,
+			}
+		}
+
+		if total < scoringGroup.MinScore {
+			scores.UnmetRequirements = append(scores.UnmetRequirements, UnmetRequirement{
+				ScoringGroup: scoringGroup.Name,
+				Score:        total,
+				MinScore:     scoringGroup.MinScore,
+			})
+		}
+	}
+
+	for i := range scores.Groups {
+		group := &scores.Groups[i]
+		for j := range group.Tasks {
+			if !group.Tasks[j].Dropped {
+				group.Score += group.Tasks[j].Score
+			}
+			if !group.Hidden && currentDeadlines.Assignments[i].Tasks[j].Required && group.Tasks[j].Status != TaskStatusSuccess {
+				scores.UnmetRequirements = append(scores.UnmetRequirements, UnmetRequirement{
+					Task:     group.Tasks[j].Task,
+					Score:    group.Tasks[j].Score,
+					MinScore: group.Tasks[j].MaxScore,
+				})
+			}
+		}
+		scores.Score += group.Score
+	}
+
+	scores.FinalGrade = currentDeadlines.Scoring.FinalGrade(marks)
+	if scores.AtRisk() {
+		if markCap := currentDeadlines.Scoring.FailedMarkCap; markCap != nil && scores.FinalMark > *markCap {
+			scores.FinalMark = *markCap
+		}
+		if grading := currentDeadlines.Scoring.Grade; grading != nil && *scores.FinalGrade > grading.FailGrade {
+			*scores.FinalGrade = grading.FailGrade
 		}
 	}
 
@@ -368,14 +631,32 @@ func makeShortTaskName(name string) string {
 	return path.Base(name)
 }
 
+// isAfterFinalDeadline reports whether the pipeline was started after the final deadline.
+// Extensions may move the deadline past the final one.
+func (s Scorer) isAfterFinalDeadline(deadlines *deadlines.Deadlines, deadline time.Time, pipeline *models.Pipeline) bool {
+	finalDeadline := deadlines.Scoring.FinalDeadline
+	if finalDeadline == nil || deadline.After(finalDeadline.Time) {
+		return false
+	}
+	return pipeline.StartedAt.After(finalDeadline.Time)
+}
+
 func (s Scorer) scorePipeline(
 	policy deadlines.ScoringPolicy,
 	deadlines *deadlines.Deadlines,
-	user *models.User,
+	retake *deadlines.RetakePolicy,
 	task *deadlines.Task,
-	group *deadlines.TaskGroup,
+	deadline time.Time,
+	lateDeadline time.Time,
 	pipeline *models.Pipeline,
+	explanation *Explanation,
 ) int {
+	explanation.PipelineID = pipeline.ID
+	explanation.Status = pipeline.Status
+	explanation.SubmittedAt = pipeline.StartedAt
+	explanation.Deadline = deadline
+	explanation.Fraction = pipeline.ScoreFraction()
+
 	if pipeline.Status != models.PipelineStatusSuccess {
 		return 0
 	}
@@ -383,22 +664,27 @@ func (s Scorer) scorePipeline(
 		return -1
 	}
 
+	// Partial credit reported by the grader is applied before the late policy
+	earned := int(math.Round(float64(task.Score) * pipeline.ScoreFraction()))
+
 	score := func() int {
-		if deadline := deadlines.Scoring.FinalDeadline; deadline != nil {
-			if pipeline.StartedAt.After(deadline.Time) {
-				return 0
-			}
+		explanation.Multiplier = 1.0
+		if retake.IgnoresDeadlines() {
+			return earned
 		}
-		return policy.Score(task.Score, group.Deadline.Time, pipeline.StartedAt)
+		if s.isAfterFinalDeadline(deadlines, deadline, pipeline) {
+			explanation.FinalDeadline = true
+			explanation.Multiplier = 0.0
+			return 0
+		}
+		// The multiplier is recorded as is, the score is truncated after scaling
+		explanation.Multiplier = policy.Share(lateDeadline, pipeline.StartedAt)
+		return policy.Score(earned, lateDeadline, pipeline.StartedAt)
 	}()
 
-	// TODO(sskvor): Support different retake policies
-	if user.HasRetake {
-		minScore := int(float64(task.Score) * deadlines.Scoring.RetakePenalty)
-		if minScore > score {
-			score = minScore
-		}
+	retakeScore := retake.Apply(task.Score, score)
+	if retakeScore != score {
+		explanation.Retake = retake.Name
 	}
-
-	return score
+	return retakeScore
 }
@@ -1,16 +1,40 @@
 package scorer
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
 	"testing"
 	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
 	"github.com/bigredeye/notmanytask/internal/deadlines"
 	"github.com/bigredeye/notmanytask/internal/models"
-	"gopkg.in/yaml.v2"
 )
 
 const someStrangeDeadlines = `
-- group:    42-cpp-sucks
+scoring:
+  policies:
+  - name: linear
+    kind: linear
+    spec:
+      after: 168h
+      multiplier: 0.5
+  - name: exp
+    kind: exp
+    spec:
+      multiplier: 120h
+      threshold: 0.3
+  groups:
+  - name: moon
+    weight: 1
+    policy: exp
+  defaultGroup: moon
+assignments:
+- title:    42-cpp-sucks
   start:    17-07-1968 18:00
   deadline: 20-07-1969 23:17
   tasks:
@@ -24,6 +48,76 @@ const someStrangeDeadlines = `
       score: 300
 `
 
+const testGroup = "hse"
+
+// testProjects names the project after the gitlab login
+type testProjects struct{}
+
+func (testProjects) MakeProjectURL(user *models.User) string {
+	return "https://gitlab.com/" + *user.GitlabLogin
+}
+
+func (testProjects) MakeProjectName(user *models.User) string {
+	return *user.GitlabLogin
+}
+
+func (testProjects) MakePipelineURL(user *models.User, pipeline *models.Pipeline) string {
+	return ""
+}
+
+func (testProjects) MakeBranchURL(user *models.User, pipeline *models.Pipeline) string {
+	return ""
+}
+
+func (testProjects) MakeTaskURL(task string) string {
+	return ""
+}
+
+// newTestScorer serves the deadlines to the test group from the local file.
+func newTestScorer(t *testing.T, deadlinesYaml string) (*Scorer, database.Store) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "deadlines.yml")
+	if err := os.WriteFile(path, []byte(deadlinesYaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &config.Config{
+		Groups: config.GroupsConfig{{Name: testGroup, DeadlinesURL: "file://" + path, DeadlinesFormat: "v2"}},
+	}
+	fetcher, err := deadlines.NewFetcher(conf, zap.NewNop())
+	if err != nil || fetcher.GroupDeadlines(testGroup) == nil {
+		t.Fatal("Failed to load deadlines:", err)
+	}
+
+	db := database.NewMemoryStore()
+	return NewScorer(db, fetcher, testProjects{}), db
+}
+
+var lastGitlabID = 0
+
+func addTestUser(t *testing.T, db database.Store, login, lastName string) *models.User {
+	t.Helper()
+	user, err := db.AddUser(&models.User{FirstName: "Ivan", LastName: lastName, GroupName: testGroup})
+	if err != nil {
+		t.Fatal("Failed to add user:", err)
+	}
+	lastGitlabID++
+	gitlabID := lastGitlabID
+	if err = db.SetUserGitlabAccount(user.ID, &models.GitlabUser{GitlabID: &gitlabID, GitlabLogin: &login}); err != nil {
+		t.Fatal("Failed to set gitlab account:", err)
+	}
+	user, err = db.FindUserByGitlabLogin(login)
+	if err != nil {
+		t.Fatal("Failed to find user:", err)
+	}
+	repository := "https://gitlab.com/" + login
+	user.Repository = &repository
+	if err = db.SetUserRepository(user); err != nil {
+		t.Fatal("Failed to set repository:", err)
+	}
+	return user
+}
+
 func mustParse(t time.Time, err error) time.Time {
 	if err != nil {
 		panic(err)
@@ -38,55 +132,301 @@ func makePipeline(timePoint string, status models.PipelineStatus) *models.Pipeli
 	}
 }
 
-func checkScore(t *testing.T, groups deadlines.Deadlines, submitDate string, expectedScore int, scorer scoringFunc) {
-	score := scorer(&groups[0].Tasks[1], &groups[0], makePipeline(submitDate, models.PipelineStatusSuccess))
+var lastPipelineID = 0
+
+func addTestPipeline(t *testing.T, db database.Store, login, task, timePoint string, status models.PipelineStatus) *models.Pipeline {
+	t.Helper()
+	lastPipelineID++
+	pipeline := makePipeline(timePoint, status)
+	pipeline.ID = lastPipelineID
+	pipeline.Project = login
+	pipeline.Task = task
+	if err := db.AddPipeline(pipeline); err != nil {
+		t.Fatal("Failed to add pipeline:", err)
+	}
+	return pipeline
+}
+
+func findPolicy(d *deadlines.Deadlines, name string) deadlines.ScoringPolicy {
+	for _, spec := range d.Scoring.Policies {
+		if spec.Name == name {
+			return spec.Policy
+		}
+	}
+	return nil
+}
+
+func findTask(scores *UserScores, task string) *ScoredTask {
+	for i := range scores.Groups {
+		for j := range scores.Groups[i].Tasks {
+			if scores.Groups[i].Tasks[j].Task == task {
+				return &scores.Groups[i].Tasks[j]
+			}
+		}
+	}
+	return nil
+}
+
+func checkScore(t *testing.T, d *deadlines.Deadlines, policyName, submitDate string, expectedScore int, status models.PipelineStatus) {
+	t.Helper()
+	group := &d.Assignments[0]
+	score := Scorer{}.scorePipeline(findPolicy(d, policyName), d, nil, &group.Tasks[1], group.Deadline.Time, group.Deadline.Time, makePipeline(submitDate, status), &Explanation{})
 	if score != expectedScore {
 		t.Fatalf("Invalid score: %d, expected: %d", score, expectedScore)
 	}
 }
 
-func checkFailedScore(t *testing.T, groups deadlines.Deadlines, submitDate string, scorer scoringFunc, status models.PipelineStatus) {
-	score := scorer(&groups[0].Tasks[1], &groups[0], makePipeline(submitDate, status))
-	if score != 0 {
-		t.Fatalf("Invalid score: %d, expected: %d", score, 0)
+func TestLinearScoring(t *testing.T) {
+	scorer, _ := newTestScorer(t, someStrangeDeadlines)
+	groups := scorer.deadlines.GroupDeadlines(testGroup)
+
+	checkScore(t, groups, "linear", "19-07-1969 23:00", 9000, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "19-07-1979 23:00", 4500, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "20-07-1969 20:18", 8999, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "20-07-1969 21:17", 8973, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "21-07-1969 13:17", 8544, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "22-07-1969 13:17", 7901, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "27-07-1969 20:14", 4501, models.PipelineStatusSuccess)
+	checkScore(t, groups, "linear", "19-07-1969 23:00", 0, models.PipelineStatusPending)
+	checkScore(t, groups, "linear", "19-07-1969 23:00", 0, models.PipelineStatusRunning)
+	checkScore(t, groups, "linear", "19-07-1969 23:00", 0, models.PipelineStatusFailed)
+}
+
+func TestExponentialScoring(t *testing.T) {
+	scorer, _ := newTestScorer(t, someStrangeDeadlines)
+	groups := scorer.deadlines.GroupDeadlines(testGroup)
+
+	checkScore(t, groups, "exp", "19-07-1969 23:00", 9000, models.PipelineStatusSuccess) // before deadline
+	checkScore(t, groups, "exp", "19-07-1979 23:00", 2700, models.PipelineStatusSuccess) // ten years after deadline
+	checkScore(t, groups, "exp", "20-07-1969 20:17", 9000, models.PipelineStatusSuccess) // just at deadline
+	checkScore(t, groups, "exp", "20-07-1969 20:18", 8998, models.PipelineStatusSuccess) // one minute after deadline
+	checkScore(t, groups, "exp", "20-07-1969 21:17", 8925, models.PipelineStatusSuccess) // one hour after deadline
+	checkScore(t, groups, "exp", "21-07-1969 13:17", 7811, models.PipelineStatusSuccess) // next day after deadline
+	checkScore(t, groups, "exp", "22-07-1969 13:17", 6395, models.PipelineStatusSuccess) // two days after deadline
+	checkScore(t, groups, "exp", "27-07-1969 20:14", 2700, models.PipelineStatusSuccess) // one week after deadline
+	checkScore(t, groups, "exp", "19-07-1969 23:00", 0, models.PipelineStatusPending)
+	checkScore(t, groups, "exp", "19-07-1969 23:00", 0, models.PipelineStatusRunning)
+	checkScore(t, groups, "exp", "19-07-1969 23:00", 0, models.PipelineStatusFailed)
+}
+
+func TestCalcUserScores(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	user := addTestUser(t, db, "armstrong", "Armstrong")
+
+	// Deadline is 20-07-1969 20:17 UTC
+	addTestPipeline(t, db, "armstrong", "fly-me-to-the-moon", "19-07-1969 23:00", models.PipelineStatusFailed)
+	addTestPipeline(t, db, "armstrong", "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "armstrong", "rewrite-in-rust", "21-07-1969 13:17", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "armstrong", "rewrite-in-go", "20-07-1969 10:00", models.PipelineStatusRunning)
+	addTestPipeline(t, db, "armstrong", "rewrite-in-agc-assembly", "27-07-1969 20:14", models.PipelineStatusSuccess)
+
+	if err := db.AddOverride("armstrong", "rewrite-in-go", 42, models.PipelineStatusSuccess, nil); err != nil {
+		t.Fatal("Failed to add override:", err)
+	}
+	extended := mustParse(time.Parse("02-01-2006 15:04", "28-07-1969 00:00"))
+	if err := db.AddExtension("armstrong", "rewrite-in-agc-assembly", extended, "illness"); err != nil {
+		t.Fatal("Failed to add extension:", err)
+	}
+
+	scores, err := scorer.CalcUserScores(user)
+	if err != nil {
+		t.Fatal("Failed to calc scores:", err)
+	}
+
+	expected := map[string]struct {
+		score  int
+		status TaskStatus
+	}{
+		"fly-me-to-the-moon":      {100, TaskStatusSuccess},
+		"rewrite-in-rust":         {7811, TaskStatusSuccess},
+		"rewrite-in-go":           {42, TaskStatusSuccess},
+		"rewrite-in-agc-assembly": {300, TaskStatusSuccess},
+	}
+	for task, exp := range expected {
+		scored := findTask(scores, task)
+		if scored == nil || scored.Score != exp.score || scored.Status != exp.status {
+			t.Errorf("Invalid task %s: %+v, expected %d %s", task, scored, exp.score, exp.status)
+		}
+	}
+	if !findTask(scores, "rewrite-in-go").Overridden {
+		t.Errorf("Override is not marked")
+	}
+	if findTask(scores, "rewrite-in-agc-assembly").Deadline == nil {
+		t.Errorf("Extended deadline is not reported")
+	}
+	if scores.Score != 100+7811+42+300 || scores.MaxScore != 9450 {
+		t.Errorf("Invalid total score %d / %d", scores.Score, scores.MaxScore)
 	}
 }
 
-func TestLinearScoring(t *testing.T) {
-	groups := deadlines.Deadlines{}
-	err := yaml.Unmarshal([]byte(someStrangeDeadlines), &groups)
+func TestPipelineCreditReportedBeforeGitlab(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	user := addTestUser(t, db, "armstrong", "Armstrong")
+
+	fraction := 0.5
+	lastPipelineID++
+	credit := &models.Pipeline{ID: lastPipelineID, Project: "armstrong", Task: "rewrite-in-rust", Fraction: &fraction}
+	if err := db.SetPipelineCredit(credit); err != nil {
+		t.Fatal("Failed to set pipeline credit:", err)
+	}
+
+	scores, err := scorer.CalcUserScores(user)
 	if err != nil {
-		panic(err)
+		t.Fatal("Failed to calc scores:", err)
+	}
+	if scored := findTask(scores, "rewrite-in-rust"); scored == nil || scored.Status != TaskStatusChecking || scored.Score != 0 {
+		t.Fatalf("Reported pipeline is not checking: %+v", scored)
+	}
+
+	// Next day after the deadline, the exp policy gives 7811 of 9000 points without partial credit
+	pipeline := makePipeline("21-07-1969 13:17", models.PipelineStatusSuccess)
+	pipeline.ID = credit.ID
+	pipeline.Project = credit.Project
+	pipeline.Task = credit.Task
+	if err = db.AddPipeline(pipeline); err != nil {
+		t.Fatal("Failed to add pipeline:", err)
 	}
 
-	checkScore(t, groups, "19-07-1969 23:00", 9000, linearScore)
-	checkScore(t, groups, "19-07-1979 23:00", 4500, linearScore)
-	checkScore(t, groups, "20-07-1969 20:18", 8999, linearScore)
-	checkScore(t, groups, "20-07-1969 21:17", 8973, linearScore)
-	checkScore(t, groups, "21-07-1969 13:17", 8544, linearScore)
-	checkScore(t, groups, "22-07-1969 13:17", 7901, linearScore)
-	checkScore(t, groups, "27-07-1969 20:14", 4501, linearScore)
-	checkFailedScore(t, groups, "19-07-1969 23:00", linearScore, models.PipelineStatusPending)
-	checkFailedScore(t, groups, "19-07-1969 23:00", linearScore, models.PipelineStatusRunning)
-	checkFailedScore(t, groups, "19-07-1969 23:00", linearScore, models.PipelineStatusFailed)
+	scores, err = scorer.CalcUserScores(user)
+	if err != nil {
+		t.Fatal("Failed to calc scores:", err)
+	}
+	group := &scorer.deadlines.GroupDeadlines(testGroup).Assignments[0]
+	expected := findPolicy(scorer.deadlines.GroupDeadlines(testGroup), "exp").Score(4500, group.Deadline.Time, pipeline.StartedAt)
+	scored := findTask(scores, "rewrite-in-rust")
+	if scored == nil || scored.Status != TaskStatusSuccess || scored.Score != expected || expected >= 4500 {
+		t.Fatalf("Invalid task score: %+v, expected %d", scored, expected)
+	}
 }
 
-func TestExponentialScoring(t *testing.T) {
-	groups := deadlines.Deadlines{}
-	err := yaml.Unmarshal([]byte(someStrangeDeadlines), &groups)
+func TestCalcScoreboard(t *testing.T) {
+	scorer, db := newTestScorer(t, someStrangeDeadlines)
+	armstrong := addTestUser(t, db, "armstrong", "Armstrong")
+	aldrin := addTestUser(t, db, "aldrin", "Aldrin")
+	addTestUser(t, db, "collins", "Collins")
+
+	addTestPipeline(t, db, "armstrong", "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "aldrin", "fly-me-to-the-moon", "20-07-1969 10:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "aldrin", "rewrite-in-go", "20-07-1969 11:00", models.PipelineStatusSuccess)
+	addTestPipeline(t, db, "armstrong", "rewrite-in-rust", "21-07-1969 13:17", models.PipelineStatusSuccess)
+
+	standings, err := scorer.CalcScoreboard(testGroup)
 	if err != nil {
-		panic(err)
+		t.Fatal("Failed to calc scoreboard:", err)
+	}
+	order := make([]string, 0)
+	for _, user := range standings.Users {
+		order = append(order, user.User.GitlabLogin)
+	}
+	if len(order) != 3 || order[0] != "armstrong" || order[1] != "aldrin" || order[2] != "collins" {
+		t.Fatalf("Invalid standings order: %v", order)
+	}
+
+	// Scoreboard and the single user scores use different pipeline providers
+	for _, user := range []*models.User{armstrong, aldrin} {
+		scores, err := scorer.CalcUserScores(user)
+		if err != nil {
+			t.Fatal("Failed to calc scores:", err)
+		}
+		for _, fromStandings := range standings.Users {
+			if fromStandings.User.GitlabLogin == *user.GitlabLogin && fromStandings.Score != scores.Score {
+				t.Errorf("User %s scores differ: %d in standings, %d alone", *user.GitlabLogin, fromStandings.Score, scores.Score)
+			}
+		}
+	}
+
+	// Pipelines started later than asOf are ignored
+	future := addTestPipeline(t, db, "armstrong", "rewrite-in-go", "01-01-2100 00:00", models.PipelineStatusSuccess)
+	past, err := scorer.CalcScoreboardWithFilter(testGroup, func(user *models.User) bool {
+		return *user.GitlabLogin == "armstrong"
+	}, future.StartedAt.Add(-time.Hour))
+	if err != nil {
+		t.Fatal("Failed to calc past scoreboard:", err)
+	}
+	if len(past.Users) != 1 || past.Users[0].Score != 100+7811 {
+		t.Fatalf("Invalid past standings: %+v", past.Users)
 	}
+}
 
-	checkScore(t, groups, "19-07-1969 23:00", 9000, exponentialScore) // before deadline
-	checkScore(t, groups, "19-07-1979 23:00", 2700, exponentialScore) // ten years after deadline
-	checkScore(t, groups, "20-07-1969 20:17", 9000, exponentialScore) // just at deadline
-	checkScore(t, groups, "20-07-1969 20:18", 8998, exponentialScore) // one minute after deadline
-	checkScore(t, groups, "20-07-1969 21:17", 8925, exponentialScore) // one hour after deadline
-	checkScore(t, groups, "21-07-1969 13:17", 7811, exponentialScore) // next day after deadline
-	checkScore(t, groups, "22-07-1969 13:17", 6395, exponentialScore) // two days after deadline
-	checkScore(t, groups, "27-07-1969 20:14", 2700, exponentialScore) // one week after deadline
-	checkFailedScore(t, groups, "19-07-1969 23:00", exponentialScore, models.PipelineStatusPending)
-	checkFailedScore(t, groups, "19-07-1969 23:00", exponentialScore, models.PipelineStatusRunning)
-	checkFailedScore(t, groups, "19-07-1969 23:00", exponentialScore, models.PipelineStatusFailed)
+func TestSelectPipeline(t *testing.T) {
+	withFraction := func(pipeline *models.Pipeline, fraction float64) *models.Pipeline {
+		pipeline.Fraction = &fraction
+		return pipeline
+	}
+	score := func(pipeline *models.Pipeline) int {
+		if pipeline.Status != models.PipelineStatusSuccess {
+			return 0
+		}
+		return int(100 * pipeline.ScoreFraction())
+	}
+
+	for _, test := range []struct {
+		name      string
+		strategy  string
+		pipelines []*models.Pipeline
+		expected  int
+	}{
+		{
+			name:     "first success",
+			strategy: deadlines.SelectionFirstSuccess,
+			pipelines: []*models.Pipeline{
+				makePipeline("01-09-2022 10:00", models.PipelineStatusFailed),
+				makePipeline("02-09-2022 10:00", models.PipelineStatusSuccess),
+				makePipeline("03-09-2022 10:00", models.PipelineStatusSuccess),
+			},
+			expected: 1,
+		},
+		{
+			name:     "latest failed",
+			strategy: deadlines.SelectionLatest,
+			pipelines: []*models.Pipeline{
+				makePipeline("01-09-2022 10:00", models.PipelineStatusSuccess),
+				makePipeline("02-09-2022 10:00", models.PipelineStatusFailed),
+			},
+			expected: 1,
+		},
+		{
+			name:     "latest skips running",
+			strategy: deadlines.SelectionLatest,
+			pipelines: []*models.Pipeline{
+				makePipeline("01-09-2022 10:00", models.PipelineStatusFailed),
+				makePipeline("02-09-2022 10:00", models.PipelineStatusSuccess),
+				makePipeline("03-09-2022 10:00", models.PipelineStatusRunning),
+				makePipeline("04-09-2022 10:00", models.PipelineStatusPending),
+			},
+			expected: 1,
+		},
+		{
+			name:     "latest without finished",
+			strategy: deadlines.SelectionLatest,
+			pipelines: []*models.Pipeline{
+				makePipeline("01-09-2022 10:00", models.PipelineStatusPending),
+				makePipeline("02-09-2022 10:00", models.PipelineStatusRunning),
+			},
+			expected: 0,
+		},
+		{
+			name:     "best",
+			strategy: deadlines.SelectionBest,
+			pipelines: []*models.Pipeline{
+				withFraction(makePipeline("01-09-2022 10:00", models.PipelineStatusSuccess), 0.5),
+				withFraction(makePipeline("02-09-2022 10:00", models.PipelineStatusSuccess), 0.8),
+				makePipeline("03-09-2022 10:00", models.PipelineStatusRunning),
+			},
+			expected: 1,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			expected := test.pipelines[test.expected]
+			candidates := append([]*models.Pipeline{}, test.pipelines...)
+			sort.Slice(candidates, func(i, j int) bool {
+				return pipelineLess(candidates[i], candidates[j])
+			})
+
+			if selected := selectPipeline(candidates, test.strategy, score); selected != expected {
+				t.Fatalf("Selected %s pipeline started at %s, expected %s started at %s",
+					selected.Status, selected.StartedAt, expected.Status, expected.StartedAt)
+			}
+		})
+	}
 }
@@ -16,10 +16,10 @@ import (
 type Bot struct {
 	bot *tgbotapi.BotAPI
 	log *zap.Logger
-	db  *database.DataBase
+	db  database.Store
 }
 
-func NewBot(conf *config.Config, log *zap.Logger, db *database.DataBase) (*Bot, error) {
+func NewBot(conf *config.Config, log *zap.Logger, db database.Store) (*Bot, error) {
 	if conf.Telegram == nil {
 		return nil, nil
 	}
@@ -128,5 +128,8 @@ func (b *Bot) ReplyTo(update tgbotapi.Update, text string) error {
 	msg.ParseMode = tgbotapi.ModeMarkdownV2
 	msg.ReplyToMessageID = update.Message.MessageID
 	_, err := b.bot.Send(msg)
+	if err != nil {
+		sendFailures.Inc()
+	}
 	return err
 }
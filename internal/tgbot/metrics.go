@@ -0,0 +1,13 @@
+package tgbot
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var sendFailures = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "notmanytask",
+	Subsystem: "telegram",
+	Name:      "send_failures_total",
+	Help:      "Telegram messages which failed to send.",
+})
@@ -0,0 +1,267 @@
+package web
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	lf "github.com/bigredeye/notmanytask/internal/logfield"
+	"github.com/bigredeye/notmanytask/internal/models"
+	"github.com/bigredeye/notmanytask/internal/scorer"
+)
+
+const adminAuditRecords = 20
+
+func setupAdminPanel(s *server, r *gin.Engine) {
+	admin := r.Group(s.config.Endpoints.Admin, s.validateSession(true), s.requireRole(models.RoleTA))
+	admin.GET("", s.RenderAdminPage)
+	admin.GET("/student", s.RenderAdminStudentPage)
+	admin.GET("/home", s.RenderAdminStudentHomePage)
+	admin.POST("/override", s.handleAdminOverride)
+	admin.POST("/group", s.handleAdminChangeGroup)
+	admin.POST("/retake", s.handleAdminRetake)
+	admin.POST("/role", s.requireRole(models.RoleAdmin), s.handleAdminRole)
+	admin.GET("/tokens", s.requireRole(models.RoleAdmin), s.RenderAdminTokensPage)
+	admin.POST("/tokens", s.requireRole(models.RoleAdmin), s.handleAdminCreateToken)
+	admin.POST("/tokens/revoke", s.requireRole(models.RoleAdmin), s.handleAdminRevokeToken)
+}
+
+// requireRole must follow validateSession.
+func (s *server) requireRole(role models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		if !s.userRole(user).AtLeast(role) {
+			s.logger.Warn("Access denied", lf.UserID(user.ID), zap.String("required_role", string(role)))
+			c.String(http.StatusForbidden, "Access denied")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (s *server) RenderAdminPage(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	query := strings.TrimSpace(c.Query("q"))
+
+	var students []*models.User
+	var err error
+	if query != "" {
+		students, err = s.db.SearchUsers(query)
+	}
+
+	c.HTML(http.StatusOK, "admin.tmpl", gin.H{
+		"CourseName": s.config.Server.CourseName,
+		"Title":      s.config.Server.CourseName,
+		"Config":     s.config,
+		"Query":      query,
+		"Students":   students,
+		"IsAdmin":    s.userRole(user).AtLeast(models.RoleAdmin),
+		"Error":      err,
+		"Links":      s.makeLinks(user),
+	})
+}
+
+func (s *server) RenderAdminStudentPage(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+
+	student, err := s.db.FindUserByGitlabLogin(c.Query("login"))
+	if err != nil {
+		c.HTML(http.StatusOK, "admin_student.tmpl", gin.H{
+			"CourseName": s.config.Server.CourseName,
+			"Title":      s.config.Server.CourseName,
+			"Config":     s.config,
+			"Error":      err,
+			"Links":      s.makeLinks(user),
+		})
+		return
+	}
+
+	scores, scoresErr := s.materializer.UserScores(student)
+	if scoresErr != nil {
+		s.logger.Warn("Failed to calc scores", lf.GitlabLogin(*student.GitlabLogin), zap.Error(scoresErr))
+	}
+	overrides, _ := s.db.ListUserOverrides(*student.GitlabLogin)
+	extensions, _ := s.db.ListUserExtensions(*student.GitlabLogin)
+	audit, _ := s.db.ListAuditRecords(database.AuditFilter{Target: *student.GitlabLogin, Limit: adminAuditRecords})
+
+	c.HTML(http.StatusOK, "admin_student.tmpl", gin.H{
+		"CourseName":     s.config.Server.CourseName,
+		"Title":          s.config.Server.CourseName,
+		"Config":         s.config,
+		"Student":        student,
+		"Role":           s.userRole(student),
+		"IsAdmin":        s.userRole(user).AtLeast(models.RoleAdmin),
+		"Roles":          []models.Role{models.RoleStudent, models.RoleTA, models.RoleAdmin},
+		"Scores":         scores,
+		"Overrides":      overrides,
+		"Extensions":     extensions,
+		"Audit":          audit,
+		"Tasks":          listTasks(s.deadlines.GroupDeadlines(student.GroupName)),
+		"RetakePolicies": listRetakePolicies(s.deadlines.GroupDeadlines(student.GroupName)),
+		"Message":        c.Query("message"),
+		"Failure":        c.Query("error"),
+		"Links":          s.makeLinks(user),
+	})
+}
+
+// RenderAdminStudentHomePage shows the home page exactly as the student sees it.
+func (s *server) RenderAdminStudentHomePage(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	links := s.makeLinks(user)
+
+	student, err := s.db.FindUserByGitlabLogin(c.Query("login"))
+	var scores *scorer.UserScores
+	if err == nil {
+		scores, err = s.materializer.UserScores(student)
+		links = s.makeLinks(student)
+		links.Admin = s.config.Endpoints.Admin
+	}
+	if scores != nil {
+		reverseScores(scores)
+	}
+
+	// Preview disables live updates, the events stream belongs to the current user rather than the student
+	c.HTML(http.StatusOK, "home.tmpl", gin.H{
+		"CourseName": s.config.Server.CourseName,
+		"Title":      s.config.Server.CourseName,
+		"Config":     s.config,
+		"Scores":     scores,
+		"Error":      err,
+		"Links":      links,
+		"Preview":    true,
+	})
+}
+
+func listTasks(groupDeadlines *deadlines.Deadlines) []string {
+	tasks := make([]string, 0)
+	if groupDeadlines == nil {
+		return tasks
+	}
+	for _, group := range groupDeadlines.Assignments {
+		for _, task := range group.Tasks {
+			tasks = append(tasks, task.Task)
+		}
+	}
+	return tasks
+}
+
+func listRetakePolicies(groupDeadlines *deadlines.Deadlines) []string {
+	policies := make([]string, 0)
+	if groupDeadlines == nil {
+		return policies
+	}
+	explicitDefault := false
+	for _, policy := range groupDeadlines.Scoring.RetakePolicies {
+		policies = append(policies, policy.Name)
+		explicitDefault = explicitDefault || policy.Name == deadlines.DefaultRetakePolicy
+	}
+	// Implicit default policy
+	if !explicitDefault && groupDeadlines.GetRetakePolicy(deadlines.DefaultRetakePolicy) != nil {
+		policies = append(policies, deadlines.DefaultRetakePolicy)
+	}
+	return policies
+}
+
+// redirectToStudent shows the result of the admin operation on the student page.
+func (s *server) redirectToStudent(c *gin.Context, login string, err error, message string) {
+	query := url.Values{"login": {login}}
+	if err != nil {
+		query.Set("error", err.Error())
+	} else {
+		query.Set("message", message)
+	}
+	c.Redirect(http.StatusFound, s.config.Endpoints.Admin+"/student?"+query.Encode())
+}
+
+func (s *server) handleAdminOverride(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	login := c.PostForm("login")
+
+	score, err := strconv.Atoi(c.PostForm("score"))
+	if err == nil {
+		_, err = s.overrideScore(userActor(user), login, c.PostForm("task"), score, c.PostForm("status"))
+	}
+	s.redirectToStudent(c, login, err, "Score was overridden")
+}
+
+func (s *server) handleAdminChangeGroup(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	login := c.PostForm("login")
+
+	_, err := s.changeGroup(userActor(user), login, c.PostForm("group"))
+	s.redirectToStudent(c, login, err, "Group was changed")
+}
+
+func (s *server) handleAdminRetake(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	login := c.PostForm("login")
+
+	_, err := s.setRetakePolicy(userActor(user), login, c.PostForm("policy"))
+	s.redirectToStudent(c, login, err, "Retake policy was changed")
+}
+
+func (s *server) handleAdminRole(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	login := c.PostForm("login")
+
+	_, err := s.setRole(userActor(user), login, c.PostForm("role"))
+	s.redirectToStudent(c, login, err, "Role was changed")
+}
+
+func (s *server) RenderAdminTokensPage(c *gin.Context) {
+	s.renderAdminTokens(c, "", "", nil)
+}
+
+// renderAdminTokens is used instead of redirect, the created token must not get into the url.
+func (s *server) renderAdminTokens(c *gin.Context, created, message string, failure error) {
+	user := c.MustGet("user").(*models.User)
+
+	tokens, err := s.db.ListAPITokens()
+	c.HTML(http.StatusOK, "admin_tokens.tmpl", gin.H{
+		"CourseName": s.config.Server.CourseName,
+		"Title":      s.config.Server.CourseName,
+		"Config":     s.config,
+		"Tokens":     tokens,
+		"Scopes":     models.AllScopes,
+		"Created":    created,
+		"Message":    message,
+		"Failure":    failure,
+		"Error":      err,
+		"Links":      s.makeLinks(user),
+	})
+}
+
+func (s *server) handleAdminCreateToken(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	name := strings.TrimSpace(c.PostForm("name"))
+
+	expiresAt, err := parseTokenExpiration(c.PostForm("expires"))
+	if err != nil {
+		s.renderAdminTokens(c, "", "", err)
+		return
+	}
+	created, _, err := s.createAPIToken(userActor(user), name, c.PostFormArray("scope"), c.PostForm("group"), expiresAt)
+	if err != nil {
+		s.renderAdminTokens(c, "", "", err)
+		return
+	}
+	s.renderAdminTokens(c, created, "Token "+name+" was created, copy it now, it is shown only once", nil)
+}
+
+func (s *server) handleAdminRevokeToken(c *gin.Context) {
+	user := c.MustGet("user").(*models.User)
+	name := c.PostForm("name")
+
+	if _, err := s.revokeAPIToken(userActor(user), name); err != nil {
+		s.renderAdminTokens(c, "", "", err)
+		return
+	}
+	s.renderAdminTokens(c, "", "Token "+name+" was revoked", nil)
+}
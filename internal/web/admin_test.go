@@ -0,0 +1,155 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+var lastRoleUserGitlabID = 1000
+
+func addTestRoleUser(t *testing.T, db database.Store, login string, role models.Role) *models.User {
+	t.Helper()
+	lastRoleUserGitlabID++
+	user := addTestStudent(t, db, "hse", login, lastRoleUserGitlabID)
+	if role != "" {
+		user.Role = role
+		if err := db.SetUserRole(user); err != nil {
+			t.Fatal("Failed to set role:", err)
+		}
+	}
+	return user
+}
+
+func TestUserRole(t *testing.T) {
+	conf := &config.Config{Roles: config.RolesConfig{Admins: []string{"boss", "ta-boss"}, TAs: []string{"helper", "chief"}}}
+	s := newTestAPIService(conf).server
+
+	for _, tc := range []struct {
+		login    string
+		stored   models.Role
+		expected models.Role
+	}{
+		{"ivanov", "", ""},
+		{"petrov", models.RoleTA, models.RoleTA},
+		{"helper", "", models.RoleTA},
+		{"boss", "", models.RoleAdmin},
+		// Config grants roles on top of the stored one and never lowers it
+		{"ta-boss", models.RoleTA, models.RoleAdmin},
+		{"chief", models.RoleAdmin, models.RoleAdmin},
+	} {
+		user := addTestRoleUser(t, s.db, tc.login, tc.stored)
+		if role := s.userRole(user); role != tc.expected {
+			t.Errorf("Invalid role of %s: %q, expected %q", tc.login, role, tc.expected)
+		}
+	}
+
+	if role := s.userRole(&models.User{Role: models.RoleTA}); role != models.RoleTA {
+		t.Errorf("Invalid role of user without gitlab account: %q", role)
+	}
+}
+
+func TestRoleAtLeast(t *testing.T) {
+	roles := []models.Role{models.RoleStudent, models.RoleTA, models.RoleAdmin}
+	for i, role := range roles {
+		for j, required := range roles {
+			if role.AtLeast(required) != (i >= j) {
+				t.Errorf("Invalid %q.AtLeast(%q): %v", role, required, role.AtLeast(required))
+			}
+		}
+	}
+	if !models.Role("").AtLeast(models.RoleStudent) || models.Role("").AtLeast(models.RoleTA) {
+		t.Errorf("Empty role is not a student")
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.Config{Roles: config.RolesConfig{Admins: []string{"boss"}, TAs: []string{"helper"}}}
+	conf.Endpoints.Admin = "/admin"
+	conf.Server.Cookies.AuthenticationKey = strings.Repeat("01", 32)
+	conf.Server.Cookies.EncryptionKey = strings.Repeat("02", 32)
+	s := newTestAPIService(conf).server
+
+	r := gin.New()
+	if err := setupAuth(s, r); err != nil {
+		t.Fatal("Failed to setup auth:", err)
+	}
+	r.GET("/test/login/:token", func(c *gin.Context) {
+		storage := sessions.Default(c)
+		storage.Set(sessionKeyToken, c.Param("token"))
+		_ = storage.Save()
+	})
+	setupAdminPanel(s, r)
+
+	login := func(user *models.User) string {
+		session, err := s.db.CreateSession(user.ID)
+		if err != nil {
+			t.Fatal("Failed to create session:", err)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/login/"+session.Token, nil))
+		return w.Header().Get("Set-Cookie")
+	}
+	cookies := map[string]string{
+		"student":     login(addTestRoleUser(t, s.db, "ivanov", "")),
+		"ta":          login(addTestRoleUser(t, s.db, "petrov", models.RoleTA)),
+		"configTA":    login(addTestRoleUser(t, s.db, "helper", "")),
+		"admin":       login(addTestRoleUser(t, s.db, "sidorov", models.RoleAdmin)),
+		"configAdmin": login(addTestRoleUser(t, s.db, "boss", "")),
+	}
+
+	// Forms of unknown students only redirect back, the handlers are reached without changes
+	form := url.Values{"login": {"unknown"}}.Encode()
+	for _, tc := range []struct {
+		path    string
+		allowed []string
+	}{
+		{"/admin/retake", []string{"ta", "configTA", "admin", "configAdmin"}},
+		{"/admin/group", []string{"ta", "configTA", "admin", "configAdmin"}},
+		{"/admin/role", []string{"admin", "configAdmin"}},
+	} {
+		allowed := make(map[string]bool)
+		for _, who := range tc.allowed {
+			allowed[who] = true
+		}
+		for who, cookie := range cookies {
+			req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(form))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Cookie", cookie)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			reached := w.Code == http.StatusFound && strings.HasPrefix(w.Header().Get("Location"), "/admin/student?")
+			if reached != allowed[who] || !reached && w.Code != http.StatusForbidden {
+				t.Errorf("Invalid access of %s to %s: %d", who, tc.path, w.Code)
+			}
+		}
+	}
+
+	// Token pages are admin-only, TAs are denied before rendering
+	for _, route := range []struct{ method, path string }{
+		{http.MethodGet, "/admin/tokens"},
+		{http.MethodPost, "/admin/tokens"},
+		{http.MethodPost, "/admin/tokens/revoke"},
+	} {
+		for _, who := range []string{"student", "ta", "configTA"} {
+			req := httptest.NewRequest(route.method, route.path, nil)
+			req.Header.Set("Cookie", cookies[who])
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != http.StatusForbidden || w.Body.String() != "Access denied" {
+				t.Errorf("Invalid access of %s to %s %s: %d %q", who, route.method, route.path, w.Code, w.Body.String())
+			}
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package web
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/bigredeye/notmanytask/api"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/export"
 	lf "github.com/bigredeye/notmanytask/internal/logfield"
+	"github.com/bigredeye/notmanytask/internal/models"
+	"github.com/bigredeye/notmanytask/internal/scorer"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -18,12 +24,23 @@ type apiService struct {
 func setupApiService(server *server, r *gin.Engine) error {
 	s := apiService{webService{server, server.config, server.logger}}
 
-	r.POST(server.config.Endpoints.Api.Report, s.report)
-	r.POST(server.config.Endpoints.Api.Flag, s.createFlag)
-	r.POST(server.config.Endpoints.Api.Override, s.validateToken, s.override)
-	r.POST(server.config.Endpoints.Api.ChangeGroup, s.changeGroup)
-	r.GET(server.config.Endpoints.Api.Standings, s.validateToken, s.standings)
-	r.GET(server.config.Endpoints.Api.ListGroupMembers, s.validateToken, s.listGroupMembers)
+	r.POST(server.config.Endpoints.Api.Report, s.requireScope(models.ScopeReport), s.report)
+	r.POST(server.config.Endpoints.Api.Flag, s.requireScope(models.ScopeFlag), s.createFlag)
+	r.POST(server.config.Endpoints.Api.Override, s.requireScope(models.ScopeOverride), s.override)
+	r.POST(server.config.Endpoints.Api.Extension, s.requireScope(models.ScopeOverride), s.addExtension)
+	r.GET(server.config.Endpoints.Api.Extension, s.requireScope(models.ScopeReadStandings), s.listExtensions)
+	r.DELETE(server.config.Endpoints.Api.Extension, s.requireScope(models.ScopeOverride), s.removeExtension)
+	r.POST(server.config.Endpoints.Api.ChangeGroup, s.requireScope(models.ScopeManageUsers), s.changeGroup)
+	r.POST(server.config.Endpoints.Api.Retake, s.requireScope(models.ScopeManageUsers), s.setRetakePolicy)
+	r.GET(server.config.Endpoints.Api.Standings, s.requireScope(models.ScopeReadStandings), s.standings)
+	r.GET(server.config.Endpoints.Api.StandingsExport, s.requireScope(models.ScopeReadStandings), s.exportStandings)
+	r.GET(server.config.Endpoints.Api.ListGroupMembers, s.requireScope(models.ScopeReadStandings), s.listGroupMembers)
+	r.GET(server.config.Endpoints.Api.DeadlinesStatus, s.requireScope(models.ScopeReadStandings), s.deadlinesStatus)
+	r.GET(server.config.Endpoints.Api.Audit, s.requireScope(models.ScopeManageUsers), s.listAudit)
+	r.POST(server.config.Endpoints.Api.Role, s.requireScope(models.ScopeManageUsers), s.setRole)
+	r.POST(server.config.Endpoints.Api.Tokens, s.requireScope(models.ScopeManageUsers), s.createToken)
+	r.GET(server.config.Endpoints.Api.Tokens, s.requireScope(models.ScopeManageUsers), s.listTokens)
+	r.DELETE(server.config.Endpoints.Api.Tokens, s.requireScope(models.ScopeManageUsers), s.revokeToken)
 
 	return nil
 }
@@ -59,19 +76,52 @@ func (s apiService) report(c *gin.Context) {
 	}
 
 	s.log.Info("Parsed report json",
-		lf.Token(req.Token),
+		zap.String("token", apiToken(c).Name),
 		lf.ProjectName(req.ProjectName),
 		lf.GitlabID(userID),
 		lf.PipelineID(id),
 		zap.String("report_status", req.Status),
 	)
 
-	if !s.isTokenValid(req.Token) {
-		s.log.Warn("Unknown token", lf.Token(req.Token))
-		onError(http.StatusUnauthorized, fmt.Errorf("invalid or expired token"))
+	// Credit and fresh pipelines are keyed by the project, user_id is the one who started the pipeline, e.g. a TA rerunning it
+	user, err := findProjectOwner(s.config.Groups, s.server.db, s.server.gitlab, req.ProjectName)
+	if err != nil {
+		s.log.Error("Failed to find project owner", lf.ProjectName(req.ProjectName), zap.Error(err))
+		onError(http.StatusInternalServerError, err)
+		return
+	}
+	if user == nil {
+		onError(http.StatusNotFound, fmt.Errorf("not found owner of project %s", req.ProjectName))
+		return
+	}
+	if code, err := s.checkGroup(c, user.GroupName); err != nil {
+		onError(code, err)
 		return
 	}
 
+	credit, err := parsePipelineCredit(&req)
+	if err != nil {
+		onError(http.StatusBadRequest, err)
+		return
+	}
+	if credit != nil {
+		credit.ID = id
+		credit.Project = req.ProjectName
+		credit.Task = req.Task
+		if err = s.server.db.SetPipelineCredit(credit); err != nil {
+			s.log.Error("Failed to store pipeline credit", lf.PipelineID(id), zap.Error(err))
+			onError(http.StatusInternalServerError, err)
+			return
+		}
+
+		target := req.ProjectName
+		if user.GitlabLogin != nil {
+			target = *user.GitlabLogin
+		}
+		s.server.audit(tokenActor(apiToken(c)), models.AuditActionReport, target, "",
+			fmt.Sprintf("pipeline=%d task=%s fraction=%g tests=%d/%d", id, req.Task, credit.ScoreFraction(), credit.TestsPassed, credit.TestsTotal))
+	}
+
 	err = s.server.pipelines.AddFresh(id, req.ProjectName)
 	if err != nil {
 		onError(http.StatusInternalServerError, err)
@@ -85,6 +135,39 @@ func (s apiService) report(c *gin.Context) {
 	)
 }
 
+// parsePipelineCredit returns nil if the grader did not report any partial credit.
+func parsePipelineCredit(req *api.ReportRequest) (*models.Pipeline, error) {
+	if req.Fraction == nil && req.Points == nil && req.Total == 0 {
+		return nil, nil
+	}
+
+	credit := &models.Pipeline{
+		Fraction:    req.Fraction,
+		TestsPassed: req.Passed,
+		TestsTotal:  req.Total,
+	}
+	if req.Points != nil {
+		if req.Fraction != nil {
+			return nil, fmt.Errorf("both fraction and points are reported")
+		}
+		if req.MaxPoints <= 0 {
+			return nil, fmt.Errorf("points are reported without max_points")
+		}
+		fraction := *req.Points / req.MaxPoints
+		credit.Fraction = &fraction
+	}
+	if credit.Fraction != nil && (*credit.Fraction < 0 || *credit.Fraction > 1) {
+		return nil, fmt.Errorf("score fraction %g is out of [0, 1]", *credit.Fraction)
+	}
+	if credit.TestsPassed == 0 && req.Failed > 0 {
+		credit.TestsPassed = credit.TestsTotal - req.Failed
+	}
+	if credit.TestsPassed < 0 || credit.TestsPassed > credit.TestsTotal {
+		return nil, fmt.Errorf("invalid tests count: %d passed of %d", credit.TestsPassed, credit.TestsTotal)
+	}
+	return credit, nil
+}
+
 func (s apiService) createFlag(c *gin.Context) {
 	s.log.Info("Handling crasme flag request")
 	onError := func(code int, err error) {
@@ -104,20 +187,20 @@ func (s apiService) createFlag(c *gin.Context) {
 	}
 
 	s.log.Info("Parsed flag request json",
-		lf.Token(req.Token),
+		zap.String("token", apiToken(c).Name),
 		zap.String("task", req.Task),
 	)
 
-	if !s.isTokenValid(req.Token) {
-		s.log.Warn("Unknown token", lf.Token(req.Token))
-		onError(http.StatusUnauthorized, fmt.Errorf("invalid or expired token"))
-		return
-	}
-
 	if !s.server.deadlines.AnyGroupHasTask(req.Task) {
 		onError(http.StatusBadRequest, fmt.Errorf("unknown task %s", req.Task))
 		return
 	}
+	if group := apiToken(c).GroupName; group != "" {
+		if groupDeadlines := s.server.deadlines.GroupDeadlines(group); groupDeadlines == nil || !groupDeadlines.HasTask(req.Task) {
+			onError(http.StatusForbidden, fmt.Errorf("token is restricted to group %s", group))
+			return
+		}
+	}
 
 	flag, err := s.server.db.CreateFlag(req.Task)
 	if err != nil {
@@ -126,6 +209,7 @@ func (s apiService) createFlag(c *gin.Context) {
 		return
 	}
 	s.log.Info("Created new flag", zap.String("flag", flag.ID), zap.String("task", flag.Task))
+	s.server.audit(tokenActor(apiToken(c)), models.AuditActionCreateFlag, "", "", "task="+flag.Task)
 
 	c.JSON(http.StatusOK, &api.FlagResponse{
 		Status: api.Status{
@@ -160,30 +244,177 @@ func (s apiService) override(c *gin.Context) {
 		zap.String("status", req.Status),
 	)
 
-	_, err := s.server.db.FindUserByGitlabLogin(req.Login)
+	if code, err := s.checkUser(c, req.Login); err != nil {
+		onError(code, err)
+		return
+	}
+	if code, err := s.server.overrideScore(tokenActor(apiToken(c)), req.Login, req.Task, req.Score, req.Status); err != nil {
+		onError(code, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.OverrideResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
+
+func (s apiService) addExtension(c *gin.Context) {
+	s.log.Info("Handling deadline extension request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to extend deadline", zap.Error(err))
+		c.JSON(code, &api.ExtensionResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	req := api.ExtensionRequest{}
+	if err := c.Bind(&req); err != nil {
+		onError(http.StatusBadRequest, fmt.Errorf("failed to parse request body: %w", err))
+		return
+	}
+
+	s.log.Info("Parsed deadline extension request json",
+		zap.String("task", req.Task),
+		zap.String("login", req.Login),
+		zap.String("deadline", req.Deadline),
+		zap.String("reason", req.Reason),
+	)
+
+	user, err := s.server.db.FindUserByGitlabLogin(req.Login)
 	if err != nil {
 		s.log.Error("Failed to get user by login", lf.GitlabLogin(req.Login))
 		onError(http.StatusNotFound, fmt.Errorf("not found user"))
 		return
 	}
+	if code, err := s.checkGroup(c, user.GroupName); err != nil {
+		onError(code, err)
+		return
+	}
 
-	if !s.server.deadlines.AnyGroupHasTask(req.Task) {
-		onError(http.StatusBadRequest, fmt.Errorf("unknown task %s", req.Task))
+	// Deadlines without explicit offset are in the time zone of the user's group
+	deadline, err := deadlines.ParseDate(req.Deadline, s.server.deadlines.GroupDeadlines(user.GroupName).Location())
+	if err != nil {
+		onError(http.StatusBadRequest, fmt.Errorf("failed to parse deadline: %w", err))
 		return
 	}
 
-	err = s.server.db.AddOverride(req.Login, req.Task, req.Score, req.Status)
+	if !s.server.deadlines.AnyGroupHasTask(req.Task) && !s.server.deadlines.AnyGroupHasTaskGroup(req.Task) {
+		onError(http.StatusBadRequest, fmt.Errorf("unknown task or task group %s", req.Task))
+		return
+	}
+
+	oldValue, err := s.findExtension(req.Login, req.Task)
 	if err != nil {
-		s.log.Error("Failed to override score", zap.String("task", req.Task), lf.GitlabLogin(req.Login), zap.Int("score", req.Score), zap.String("status", req.Status), zap.Error(err))
+		s.log.Error("Failed to list user extensions", lf.GitlabLogin(req.Login), zap.Error(err))
 		onError(http.StatusInternalServerError, err)
 		return
 	}
-	s.log.Info("Score was overriden", zap.String("task", req.Task), lf.GitlabLogin(req.Login), zap.Int("score", req.Score), zap.String("status", req.Status))
 
-	c.JSON(http.StatusOK, &api.OverrideResponse{
+	err = s.server.db.AddExtension(req.Login, req.Task, deadline.Time, req.Reason)
+	if err != nil {
+		s.log.Error("Failed to extend deadline", zap.String("task", req.Task), lf.GitlabLogin(req.Login), zap.String("deadline", req.Deadline), zap.Error(err))
+		onError(http.StatusInternalServerError, err)
+		return
+	}
+	s.log.Info("Deadline was extended", zap.String("task", req.Task), lf.GitlabLogin(req.Login), zap.String("deadline", req.Deadline))
+	s.server.audit(tokenActor(apiToken(c)), models.AuditActionExtension, req.Login, oldValue, formatExtension(req.Task, deadline.Time, req.Reason))
+	s.server.events.NotifyUser(req.Login)
+
+	c.JSON(http.StatusOK, &api.ExtensionResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
+
+func (s apiService) removeExtension(c *gin.Context) {
+	s.log.Info("Handling deadline extension removal request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to remove deadline extension", zap.Error(err))
+		c.JSON(code, &api.ExtensionResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	login, task := c.Query("login"), c.Query("task")
+	if login == "" || task == "" {
+		onError(http.StatusBadRequest, fmt.Errorf("both login and task are required"))
+		return
+	}
+	if code, err := s.checkUser(c, login); err != nil {
+		onError(code, err)
+		return
+	}
+
+	oldValue, err := s.findExtension(login, task)
+	if err != nil {
+		s.log.Error("Failed to list user extensions", lf.GitlabLogin(login), zap.Error(err))
+		onError(http.StatusInternalServerError, err)
+		return
+	}
+
+	err = s.server.db.RemoveExtension(login, task)
+	if err != nil {
+		s.log.Error("Failed to remove deadline extension", zap.String("task", task), lf.GitlabLogin(login), zap.Error(err))
+		onError(http.StatusInternalServerError, err)
+		return
+	}
+	s.log.Info("Deadline extension was removed", zap.String("task", task), lf.GitlabLogin(login))
+	if oldValue != "" {
+		s.server.audit(tokenActor(apiToken(c)), models.AuditActionRemoveExtension, login, oldValue, "")
+	}
+	s.server.events.NotifyUser(login)
+
+	c.JSON(http.StatusOK, &api.ExtensionResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
+
+func (s apiService) listExtensions(c *gin.Context) {
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to list deadline extensions", zap.Error(err))
+		c.JSON(code, &api.ListExtensionsResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	var extensions []models.Extension
+	var err error
+	if login := c.Query("login"); login != "" {
+		if code, err := s.checkUser(c, login); err != nil {
+			onError(code, err)
+			return
+		}
+		extensions, err = s.server.db.ListUserExtensions(login)
+	} else {
+		extensions, err = s.server.db.ListExtensions()
+		if err == nil && apiToken(c).GroupName != "" {
+			extensions, err = s.filterGroupExtensions(apiToken(c).GroupName, extensions)
+		}
+	}
+	if err != nil {
+		onError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.ListExtensionsResponse{
 		Status: api.Status{
 			Ok: true,
 		},
+		Extensions: extensions,
 	})
 }
 
@@ -206,46 +437,94 @@ func (s apiService) changeGroup(c *gin.Context) {
 	}
 
 	s.log.Info("Parsed change group request json",
-		lf.Token(req.Token),
+		zap.String("token", apiToken(c).Name),
 		zap.String("login", req.Login),
 		zap.String("group_name", req.GroupName),
 	)
 
-	if !s.isTokenValid(req.Token) {
-		s.log.Warn("Unknown token", lf.Token(req.Token))
-		onError(http.StatusUnauthorized, fmt.Errorf("invalid or expired token"))
+	if code, err := s.checkUser(c, req.Login); err != nil {
+		onError(code, err)
+		return
+	}
+	if code, err := s.checkGroup(c, req.GroupName); err != nil {
+		onError(code, err)
+		return
+	}
+	if code, err := s.server.changeGroup(tokenActor(apiToken(c)), req.Login, req.GroupName); err != nil {
+		onError(code, err)
 		return
 	}
 
-	user, err := s.server.db.FindUserByGitlabLogin(req.Login)
-	if err != nil {
-		s.log.Error("Failed to get user by login", lf.GitlabLogin(req.Login))
-		onError(http.StatusNotFound, fmt.Errorf("not found user"))
+	c.JSON(http.StatusOK, &api.ChangeGroupResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
+
+func (s apiService) setRetakePolicy(c *gin.Context) {
+	s.log.Info("Handling retake policy request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to set retake policy", zap.Error(err))
+		c.JSON(code, &api.RetakeResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	req := api.RetakeRequest{}
+	if err := c.Bind(&req); err != nil {
+		onError(http.StatusBadRequest, fmt.Errorf("failed to parse request body: %w", err))
 		return
 	}
 
-	groupExists := false
-	for _, group := range s.config.Groups {
-		if group.Name == req.GroupName {
-			groupExists = true
-		}
+	if code, err := s.checkUser(c, req.Login); err != nil {
+		onError(code, err)
+		return
 	}
-	if !groupExists {
-		s.log.Error("Failed to find group with name", zap.String("group_name", req.GroupName))
-		onError(http.StatusNotFound, fmt.Errorf("not found group"))
+	if code, err := s.server.setRetakePolicy(tokenActor(apiToken(c)), req.Login, req.Policy); err != nil {
+		onError(code, err)
 		return
 	}
-	user.GroupName = req.GroupName
 
-	err = s.server.db.SetUserGroupName(user)
-	if err != nil {
-		s.log.Error("Failed to change group", lf.GitlabLogin(req.Login), zap.String("group_name", req.GroupName), zap.Error(err))
-		onError(http.StatusInternalServerError, err)
+	c.JSON(http.StatusOK, &api.RetakeResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
+
+func (s apiService) setRole(c *gin.Context) {
+	s.log.Info("Handling role request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to set role", zap.Error(err))
+		c.JSON(code, &api.RoleResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	req := api.RoleRequest{}
+	if err := c.Bind(&req); err != nil {
+		onError(http.StatusBadRequest, fmt.Errorf("failed to parse request body: %w", err))
 		return
 	}
-	s.log.Info("Group was changed", lf.GitlabLogin(req.Login), zap.String("group_name", req.GroupName))
 
-	c.JSON(http.StatusOK, &api.ChangeGroupResponse{
+	// Roles grant access to the admin panel of every group, so group-restricted tokens may not set them
+	if code, err := s.checkUnrestricted(c); err != nil {
+		onError(code, err)
+		return
+	}
+	if code, err := s.server.setRole(tokenActor(apiToken(c)), req.Login, req.Role); err != nil {
+		onError(code, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.RoleResponse{
 		Status: api.Status{
 			Ok: true,
 		},
@@ -283,6 +562,7 @@ func (s apiService) userScores(c *gin.Context) {
 		onError(http.StatusInternalServerError, fmt.Errorf("failed to calc scores"))
 		return
 	}
+	s.hideGrades(user.GroupName, scores)
 
 	c.JSON(http.StatusOK, &api.UserScoresResponse{
 		Status: api.Status{
@@ -304,6 +584,10 @@ func (s apiService) listGroupMembers(c *gin.Context) {
 	}
 
 	group := c.Param("group")
+	if code, err := s.checkGroup(c, group); err != nil {
+		onError(code, err)
+		return
+	}
 	users, err := s.server.db.ListGroupUsers(group)
 	if err != nil {
 		onError(http.StatusNotFound, err)
@@ -330,45 +614,136 @@ func (s apiService) standings(c *gin.Context) {
 		)
 	}
 
+	group, standings, code, err := s.loadStandings(c)
+	if err != nil {
+		onError(code, err)
+		return
+	}
+	s.hideGrades(group, standings.Users...)
+
+	c.JSON(http.StatusOK, &api.StandingsResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+		Standings: standings,
+	})
+}
+
+func (s apiService) exportStandings(c *gin.Context) {
+	s.log.Info("Handling standings export request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to export standings", zap.Error(err))
+		c.JSON(code, &api.Status{
+			Ok:    false,
+			Error: err.Error(),
+		})
+	}
+
+	format, err := export.ParseFormat(c.Query("format"))
+	if err != nil {
+		onError(http.StatusBadRequest, err)
+		return
+	}
+	options, err := export.ParseOptions(c.Query("columns"), c.Query("names"))
+	if err != nil {
+		onError(http.StatusBadRequest, err)
+		return
+	}
+
+	// Exports are made for grading, so final grades are kept regardless of ShowMarks
+	_, standings, code, err := s.loadStandings(c)
+	if err != nil {
+		onError(code, err)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err = export.Write(&buf, format, export.BuildTable(standings, options)); err != nil {
+		onError(http.StatusInternalServerError, fmt.Errorf("failed to export standings: %w", err))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="standings.%s"`, format))
+	c.Data(http.StatusOK, format.ContentType(), buf.Bytes())
+}
+
+// loadStandings handles group and at query parameters shared by standings and export.
+func (s apiService) loadStandings(c *gin.Context) (string, *scorer.Standings, int, error) {
 	var group string
 	if g := c.Query("group"); g != "" {
 		group = g
+	} else if g := apiToken(c).GroupName; g != "" {
+		group = g
 	} else {
 		group = s.config.Groups.FindDefaultGroup().Name
 	}
+	if code, err := s.checkGroup(c, group); err != nil {
+		return "", nil, code, err
+	}
 
-	standings, err := s.server.scorer.CalcScoreboard(group)
+	var asOf time.Time
+	if at := c.Query("at"); at != "" {
+		date, err := deadlines.ParseDate(at, s.server.deadlines.GroupDeadlines(group).Location())
+		if err != nil {
+			return "", nil, http.StatusBadRequest, fmt.Errorf("failed to parse at: %w", err)
+		}
+		asOf = date.Time
+	}
+
+	standings, err := s.server.scorer.CalcScoreboardWithFilter(group, nil, asOf)
 	if err != nil {
-		onError(http.StatusInternalServerError, fmt.Errorf("failed to list scores: %w", err))
+		return "", nil, http.StatusInternalServerError, fmt.Errorf("failed to list scores: %w", err)
+	}
+	return group, standings, http.StatusOK, nil
+}
+
+// hideGrades strips final grades unless the group shows marks.
+func (s apiService) hideGrades(group string, scores ...*scorer.UserScores) {
+	if config := s.config.Groups.FindGroup(group); config != nil && config.ShowMarks {
 		return
 	}
+	for _, userScores := range scores {
+		userScores.FinalGrade = nil
+	}
+}
 
-	c.JSON(http.StatusOK, &api.StandingsResponse{
+func (s apiService) deadlinesStatus(c *gin.Context) {
+	statuses := s.server.deadlines.GroupStatuses()
+	if token := apiToken(c); token.GroupName != "" {
+		filtered := make([]deadlines.GroupStatus, 0, 1)
+		for _, status := range statuses {
+			if token.AllowsGroup(status.Group) {
+				filtered = append(filtered, status)
+			}
+		}
+		statuses = filtered
+	}
+
+	c.JSON(http.StatusOK, &api.DeadlinesStatusResponse{
 		Status: api.Status{
 			Ok: true,
 		},
-		Standings: standings,
+		Groups: statuses,
 	})
 }
 
-func (s apiService) validateToken(c *gin.Context) {
-	token := c.GetHeader("token")
-	if !s.isTokenValid(token) {
-		c.JSON(http.StatusUnauthorized, &api.Status{
-			Ok:    false,
-			Error: "Invalid or expired token",
-		})
-		c.Abort()
-		return
+func (s apiService) filterGroupExtensions(group string, extensions []models.Extension) ([]models.Extension, error) {
+	users, err := s.server.db.ListGroupUsers(group)
+	if err != nil {
+		return nil, err
+	}
+	logins := make(map[string]bool)
+	for _, user := range users {
+		if user.GitlabLogin != nil {
+			logins[*user.GitlabLogin] = true
+		}
 	}
-	c.Next()
-}
 
-func (s apiService) isTokenValid(token string) bool {
-	for _, ttoken := range s.config.Testing.Tokens {
-		if token == ttoken {
-			return true
+	filtered := make([]models.Extension, 0)
+	for _, extension := range extensions {
+		if logins[extension.GitlabLogin] {
+			filtered = append(filtered, extension)
 		}
 	}
-	return false
+	return filtered, nil
 }
@@ -0,0 +1,90 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/gitlab"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func addTestStudent(t *testing.T, db database.Store, group, login string, gitlabID int) *models.User {
+	t.Helper()
+	user, err := db.AddUser(&models.User{FirstName: "Ivan", LastName: login, GroupName: group})
+	if err != nil {
+		t.Fatal("Failed to add user:", err)
+	}
+	if err = db.SetUserGitlabAccount(user.ID, &models.GitlabUser{GitlabID: &gitlabID, GitlabLogin: &login}); err != nil {
+		t.Fatal("Failed to set gitlab account:", err)
+	}
+	user, err = db.FindUserByGitlabLogin(login)
+	if err != nil {
+		t.Fatal("Failed to find user:", err)
+	}
+	repository := "https://gitlab.com/" + login
+	user.Repository = &repository
+	if err = db.SetUserRepository(user); err != nil {
+		t.Fatal("Failed to set repository:", err)
+	}
+	return user
+}
+
+func TestReportResolvesProjectOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.Config{Groups: config.GroupsConfig{{Name: "hse"}, {Name: "ysda"}}}
+	s := newTestAPIService(conf)
+	git, err := gitlab.NewClient(conf, zap.NewNop())
+	if err != nil {
+		t.Fatal("Failed to create gitlab client:", err)
+	}
+	s.server.gitlab = git
+	if s.server.pipelines, err = gitlab.NewPipelinesFetcher(git, s.server.db); err != nil {
+		t.Fatal("Failed to create pipelines fetcher:", err)
+	}
+
+	student := addTestStudent(t, s.server.db, "hse", "ivanov", 1)
+	addTestStudent(t, s.server.db, "hse", "teacher", 2)
+	project := git.MakeProjectName(student)
+
+	grader := createTestToken(t, s, "grader", nil, string(models.ScopeReport))
+	ysda := createGroupTestToken(t, s, "ysda-grader", "ysda", nil, string(models.ScopeReport))
+
+	r := gin.New()
+	r.POST("/report", s.requireScope(models.ScopeReport), s.report)
+
+	for _, test := range []struct {
+		name    string
+		token   string
+		project string
+		code    int
+	}{
+		// The pipeline was rerun by the teacher, so user_id is not the owner
+		{name: "rerun by teacher", token: grader, project: project, code: http.StatusOK},
+		{name: "unknown project", token: grader, project: "hse-Ivan-Nobody-nobody", code: http.StatusNotFound},
+		{name: "other group token", token: ysda, project: project, code: http.StatusForbidden},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			body := `{"task": "sum", "user_id": "2", "pipeline_id": "42", "project_name": "` + test.project + `", "fraction": 0.5}`
+			req := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+test.token)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Fatalf("Unexpected code %d, expected %d: %s", w.Code, test.code, w.Body.String())
+			}
+		})
+	}
+
+	pipelines, err := s.server.db.ListProjectPipelines(project)
+	if err != nil || len(pipelines) != 1 || pipelines[0].Fraction == nil || *pipelines[0].Fraction != 0.5 {
+		t.Fatalf("Credit of the owner project is not stored: %+v, %v", pipelines, err)
+	}
+}
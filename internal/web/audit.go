@@ -0,0 +1,126 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/api"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func tokenActor(token *models.APIToken) string {
+	return "token:" + token.Name
+}
+
+func userActor(user *models.User) string {
+	if user.GitlabLogin == nil {
+		return fmt.Sprintf("user:%d", user.ID)
+	}
+	return "user:" + *user.GitlabLogin
+}
+
+func auditRecord(actor, action, target, oldValue, newValue string) *models.AuditRecord {
+	return &models.AuditRecord{
+		Actor:    actor,
+		Action:   action,
+		Target:   target,
+		OldValue: oldValue,
+		NewValue: newValue,
+	}
+}
+
+// audit never fails the request, the change is already made.
+// Changes of scores and groups write their records in the same transaction instead.
+func (s *server) audit(actor, action, target, oldValue, newValue string) {
+	err := s.db.AddAuditRecord(auditRecord(actor, action, target, oldValue, newValue))
+	if err != nil {
+		auditWriteFailures.Inc()
+		s.logger.Error("Failed to write audit record",
+			zap.String("actor", actor),
+			zap.String("action", action),
+			zap.String("target", target),
+			zap.Error(err),
+		)
+	}
+}
+
+func (s apiService) listAudit(c *gin.Context) {
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to list audit records", zap.Error(err))
+		c.JSON(code, &api.AuditResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	// Audit records are not bound to groups, e.g. token changes
+	if code, err := s.checkUnrestricted(c); err != nil {
+		onError(code, err)
+		return
+	}
+
+	filter := database.AuditFilter{
+		Actor:  c.Query("actor"),
+		Action: c.Query("action"),
+		Target: c.Query("login"),
+	}
+	if since := c.Query("since"); since != "" {
+		date := deadlines.Date{}
+		if err := date.UnmarshalText([]byte(since)); err != nil {
+			onError(http.StatusBadRequest, fmt.Errorf("failed to parse since: %w", err))
+			return
+		}
+		filter.Since = date.Time
+	}
+	if limit := c.Query("limit"); limit != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(limit); err != nil {
+			onError(http.StatusBadRequest, fmt.Errorf("failed to parse limit: %w", err))
+			return
+		}
+	}
+
+	records, err := s.server.db.ListAuditRecords(filter)
+	if err != nil {
+		onError(http.StatusInternalServerError, fmt.Errorf("failed to list audit records: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.AuditResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+		Records: records,
+	})
+}
+
+func formatOverride(task string, score int, status models.PipelineStatus) string {
+	return fmt.Sprintf("task=%s score=%d status=%s", task, score, status)
+}
+
+func formatExtension(task string, deadline time.Time, reason string) string {
+	return fmt.Sprintf("task=%s deadline=%s reason=%q", task, deadline.Format(time.RFC3339), reason)
+}
+
+// findExtension returns the formatted current extension, empty if there is none.
+func (s apiService) findExtension(login, task string) (string, error) {
+	extensions, err := s.server.db.ListUserExtensions(login)
+	if err != nil {
+		return "", err
+	}
+	for _, extension := range extensions {
+		if extension.Task == task {
+			return formatExtension(extension.Task, extension.Deadline, extension.Reason), nil
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,65 @@
+package web
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	zlog "github.com/bigredeye/notmanytask/pkg/log"
+)
+
+// CreateToken implements the token subcommand: token -name name -scope scopes [-group group] [-expires date].
+// It writes directly to the database, so the first manage-users token can be created without API.
+func CreateToken(args []string) error {
+	name := flag.String("name", "", "Unique token name")
+	scopes := flag.String("scope", "", "Comma separated scopes: report, flag, override, read-standings, manage-users")
+	group := flag.String("group", "", "Restrict token to the group students")
+	expires := flag.String("expires", "", "Expiration moment, e.g. \"31-12-2022 23:59\"")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.ParseConfig()
+	if err != nil {
+		return err
+	}
+	if driver := config.DataBase.Driver; driver != "" && driver != "postgres" {
+		return fmt.Errorf("database driver %q does not persist tokens", driver)
+	}
+
+	logger, err := zlog.Init(config.Log)
+	if err != nil {
+		return errors.Wrap(err, "Failed to init logger")
+	}
+	defer func() {
+		_ = zlog.Sync()
+	}()
+
+	db, err := database.OpenDataBase(logger.Named("database"), postgresDSN(config))
+	if err != nil {
+		return errors.Wrap(err, "Failed to open database")
+	}
+
+	expiresAt, err := parseTokenExpiration(*expires)
+	if err != nil {
+		return err
+	}
+	var scopeList []string
+	if *scopes != "" {
+		scopeList = strings.Split(*scopes, ",")
+	}
+	s := &server{config: config, logger: logger, db: db}
+	token, _, err := s.createAPIToken("cli", *name, scopeList, *group, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Created token %s, it is printed only once\n", *name)
+	fmt.Println(token)
+	return nil
+}
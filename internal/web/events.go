@@ -0,0 +1,279 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/api"
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/gitlab"
+	lf "github.com/bigredeye/notmanytask/internal/logfield"
+	"github.com/bigredeye/notmanytask/internal/models"
+	"github.com/bigredeye/notmanytask/internal/scorer"
+)
+
+const (
+	// eventsDebounce merges bursts of changes, e.g. the grader report followed by the pipeline status
+	eventsDebounce  = time.Second
+	eventsKeepAlive = 30 * time.Second
+	eventsBuffer    = 16
+	eventsQueueSize = 1024
+)
+
+// scoreChange is identified either by the gitlab login or by the project name.
+type scoreChange struct {
+	login   string
+	project string
+}
+
+// eventFilter selects either the events of the single user or the events of the whole group.
+type eventFilter struct {
+	login string
+	group string
+}
+
+// eventHub recalculates the scores of the changed users and pushes the difference to the subscribers.
+type eventHub struct {
+	config       *config.Config
+	logger       *zap.Logger
+	db           database.Store
+	gitlab       *gitlab.Client
+	materializer *scorer.Materializer
+
+	changes chan scoreChange
+
+	mu          sync.Mutex
+	subscribers map[chan *api.ScoreEvent]eventFilter
+	// last keeps the latest pushed scores to send only the changed tasks
+	last map[string]*api.ScoreEvent
+}
+
+func newEventHub(config *config.Config, logger *zap.Logger, db database.Store, gitlab *gitlab.Client, materializer *scorer.Materializer) *eventHub {
+	return &eventHub{
+		config:       config,
+		logger:       logger,
+		db:           db,
+		gitlab:       gitlab,
+		materializer: materializer,
+		changes:      make(chan scoreChange, eventsQueueSize),
+		subscribers:  make(map[chan *api.ScoreEvent]eventFilter),
+		last:         make(map[string]*api.ScoreEvent),
+	}
+}
+
+func (h *eventHub) notify(change scoreChange) {
+	select {
+	case h.changes <- change:
+	default:
+		h.logger.Warn("Score changes queue is full, dropping change", lf.GitlabLogin(change.login), lf.ProjectName(change.project))
+	}
+}
+
+func (h *eventHub) NotifyUser(login string) {
+	h.notify(scoreChange{login: login})
+}
+
+func (h *eventHub) NotifyProject(project string) {
+	h.notify(scoreChange{project: project})
+}
+
+func (h *eventHub) Run(ctx context.Context) {
+	pending := make(map[scoreChange]bool)
+	var flush <-chan time.Time
+
+	for {
+		select {
+		case change := <-h.changes:
+			pending[change] = true
+			if flush == nil {
+				flush = time.After(eventsDebounce)
+			}
+		case <-flush:
+			for change := range pending {
+				h.refresh(change)
+			}
+			pending = make(map[scoreChange]bool)
+			flush = nil
+		case <-ctx.Done():
+			h.logger.Info("Stopping events hub")
+			return
+		}
+	}
+}
+
+func (h *eventHub) findUser(change scoreChange) (*models.User, error) {
+	if change.login != "" {
+		return h.db.FindUserByGitlabLogin(change.login)
+	}
+	return findProjectOwner(h.config.Groups, h.db, h.gitlab, change.project)
+}
+
+func (h *eventHub) refresh(change scoreChange) {
+	user, err := h.findUser(change)
+	if err != nil || user == nil || user.GitlabLogin == nil {
+		h.logger.Warn("Failed to find changed user", lf.GitlabLogin(change.login), lf.ProjectName(change.project), zap.Error(err))
+		return
+	}
+
+	scores, err := h.materializer.Refresh(user)
+	if err != nil {
+		h.logger.Error("Failed to refresh scores", lf.GitlabLogin(*user.GitlabLogin), zap.Error(err))
+		return
+	}
+
+	event := h.makeEvent(user, scores)
+	h.mu.Lock()
+	diff := diffScoreEvents(h.last[event.Login], event)
+	h.last[event.Login] = event
+	h.mu.Unlock()
+
+	if diff != nil {
+		h.publish(user.GroupName, diff)
+	}
+}
+
+func (h *eventHub) makeEvent(user *models.User, scores *scorer.UserScores) *api.ScoreEvent {
+	event := &api.ScoreEvent{
+		Login:    *user.GitlabLogin,
+		Score:    scores.Score,
+		MaxScore: scores.MaxScore,
+	}
+	if group := h.config.Groups.FindGroup(user.GroupName); group != nil && group.ShowMarks {
+		mark := scores.FinalMark
+		event.Mark = &mark
+	}
+	for _, group := range scores.Groups {
+		event.Groups = append(event.Groups, api.GroupScoreEvent{
+			Title:    group.Title,
+			Score:    group.Score,
+			MaxScore: group.MaxScore,
+		})
+		for _, task := range group.Tasks {
+			event.Tasks = append(event.Tasks, api.TaskScoreEvent{
+				Task:       task.Task,
+				Score:      task.Score,
+				MaxScore:   task.MaxScore,
+				Status:     task.Status,
+				Overridden: task.Overridden,
+				Retaken:    task.Retaken,
+				Dropped:    task.Dropped,
+			})
+		}
+	}
+	return event
+}
+
+// diffScoreEvents keeps only the changed groups and tasks, nil means nothing has changed.
+func diffScoreEvents(previous, current *api.ScoreEvent) *api.ScoreEvent {
+	if previous == nil {
+		return current
+	}
+
+	diff := &api.ScoreEvent{
+		Login:    current.Login,
+		Score:    current.Score,
+		MaxScore: current.MaxScore,
+		Mark:     current.Mark,
+	}
+	groups := make(map[string]api.GroupScoreEvent)
+	for _, group := range previous.Groups {
+		groups[group.Title] = group
+	}
+	for _, group := range current.Groups {
+		if old, found := groups[group.Title]; !found || old != group {
+			diff.Groups = append(diff.Groups, group)
+		}
+	}
+	tasks := make(map[string]api.TaskScoreEvent)
+	for _, task := range previous.Tasks {
+		tasks[task.Task] = task
+	}
+	for _, task := range current.Tasks {
+		if old, found := tasks[task.Task]; !found || old != task {
+			diff.Tasks = append(diff.Tasks, task)
+		}
+	}
+
+	sameMark := (previous.Mark == nil) == (current.Mark == nil) && (current.Mark == nil || *previous.Mark == *current.Mark)
+	if len(diff.Groups) == 0 && len(diff.Tasks) == 0 && previous.Score == current.Score && sameMark {
+		return nil
+	}
+	return diff
+}
+
+func (h *eventHub) publish(group string, event *api.ScoreEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for subscriber, filter := range h.subscribers {
+		if filter.login != event.Login && (filter.login != "" || filter.group != group) {
+			continue
+		}
+		select {
+		case subscriber <- event:
+		default:
+			h.logger.Debug("Subscriber is too slow, dropping score event", lf.GitlabLogin(event.Login))
+		}
+	}
+}
+
+func (h *eventHub) subscribe(filter eventFilter) (<-chan *api.ScoreEvent, func()) {
+	events := make(chan *api.ScoreEvent, eventsBuffer)
+
+	h.mu.Lock()
+	h.subscribers[events] = filter
+	h.mu.Unlock()
+
+	return events, func() {
+		h.mu.Lock()
+		delete(h.subscribers, events)
+		h.mu.Unlock()
+	}
+}
+
+// handleEvents streams score events of the group for the standings page
+// or the events of the current user for the home page if the group is not set.
+func (s *server) handleEvents(c *gin.Context) {
+	filter := eventFilter{group: c.Query("group")}
+	if filter.group == "" {
+		user, session, err := s.tryFindUserByToken(c)
+		if err != nil || session == nil || user.GitlabLogin == nil {
+			c.Status(http.StatusUnauthorized)
+			return
+		}
+		filter.login = *user.GitlabLogin
+	}
+
+	events, unsubscribe := s.events.subscribe(filter)
+	defer unsubscribe()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// Disable nginx buffering of the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event := <-events:
+			c.SSEvent("score", event)
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bigredeye/notmanytask/api"
+)
+
+func makeScoreEvent(mark *float64, tasks ...api.TaskScoreEvent) *api.ScoreEvent {
+	event := &api.ScoreEvent{Login: "ivanov", MaxScore: 200, Mark: mark, Tasks: tasks}
+	for _, task := range tasks {
+		event.Score += task.Score
+	}
+	event.Groups = []api.GroupScoreEvent{{Title: "01-intro", Score: event.Score, MaxScore: 200}}
+	return event
+}
+
+func TestDiffScoreEvents(t *testing.T) {
+	five, six := 5.0, 6.0
+	first := api.TaskScoreEvent{Task: "first", Score: 100, MaxScore: 100, Status: "success"}
+	second := api.TaskScoreEvent{Task: "second", Score: 0, MaxScore: 100, Status: "running"}
+	solved := api.TaskScoreEvent{Task: "second", Score: 100, MaxScore: 100, Status: "success"}
+
+	for _, test := range []struct {
+		name     string
+		previous *api.ScoreEvent
+		current  *api.ScoreEvent
+		expected *api.ScoreEvent
+	}{
+		{
+			name:     "first event",
+			current:  makeScoreEvent(nil, first, second),
+			expected: makeScoreEvent(nil, first, second),
+		},
+		{
+			name:     "unchanged",
+			previous: makeScoreEvent(&five, first, second),
+			current:  makeScoreEvent(&five, first, second),
+		},
+		{
+			name:     "changed task",
+			previous: makeScoreEvent(nil, first, second),
+			current:  makeScoreEvent(nil, first, solved),
+			expected: &api.ScoreEvent{
+				Login: "ivanov", Score: 200, MaxScore: 200,
+				Groups: []api.GroupScoreEvent{{Title: "01-intro", Score: 200, MaxScore: 200}},
+				Tasks:  []api.TaskScoreEvent{solved},
+			},
+		},
+		{
+			name:     "new task",
+			previous: makeScoreEvent(nil, first),
+			current:  makeScoreEvent(nil, first, second),
+			expected: &api.ScoreEvent{
+				Login: "ivanov", Score: 100, MaxScore: 200,
+				Tasks: []api.TaskScoreEvent{second},
+			},
+		},
+		{
+			name:     "changed mark",
+			previous: makeScoreEvent(&five, first),
+			current:  makeScoreEvent(&six, first),
+			expected: &api.ScoreEvent{Login: "ivanov", Score: 100, MaxScore: 200, Mark: &six},
+		},
+		{
+			name:     "shown mark",
+			previous: makeScoreEvent(nil, first),
+			current:  makeScoreEvent(&five, first),
+			expected: &api.ScoreEvent{Login: "ivanov", Score: 100, MaxScore: 200, Mark: &five},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			diff := diffScoreEvents(test.previous, test.current)
+			if !reflect.DeepEqual(diff, test.expected) {
+				t.Fatalf("Invalid diff: %+v, expected %+v", diff, test.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "notmanytask",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of HTTP requests by route.",
+	}, []string{"method", "route", "code"})
+
+	standingsCacheRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "notmanytask",
+		Subsystem: "standings_cache",
+		Name:      "requests_total",
+		Help:      "Standings page cache lookups by result, either hit or miss.",
+	}, []string{"result"})
+
+	auditWriteFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "notmanytask",
+		Subsystem: "audit",
+		Name:      "write_failures_total",
+		Help:      "Audit records that were not written after the change was made.",
+	})
+)
+
+// observeRequests labels requests by the route pattern, not by the path, to keep the number of series bounded.
+// Streaming routes live as long as the client is connected, their duration would skew the latency histogram.
+func observeRequests(streaming ...string) gin.HandlerFunc {
+	skip := make(map[string]bool)
+	for _, route := range streaming {
+		skip[route] = true
+	}
+
+	return func(c *gin.Context) {
+		if skip[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		started := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		requestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Observe(time.Since(started).Seconds())
+	}
+}
+
+func observeStandingsCache(hit bool) {
+	if hit {
+		standingsCacheRequests.WithLabelValues("hit").Inc()
+	} else {
+		standingsCacheRequests.WithLabelValues("miss").Inc()
+	}
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func TestObserveRequestsSkipsStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(observeRequests("/test/events"))
+	r.GET("/test/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/test/events", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/test/ping", "/test/events", "/test/ping"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if count := testutil.CollectAndCount(requestDuration, "notmanytask_http_request_duration_seconds"); count != 1 {
+		t.Fatalf("Expected only the ping route to be observed, found %d series", count)
+	}
+}
+
+// failingAuditStore fails to write audit records written outside of the changes
+type failingAuditStore struct {
+	database.Store
+}
+
+func (failingAuditStore) AddAuditRecord(record *models.AuditRecord) error {
+	return errors.New("audit is unavailable")
+}
+
+func TestAuditCountsWriteFailures(t *testing.T) {
+	s := &server{db: failingAuditStore{database.NewMemoryStore()}, logger: zap.NewNop()}
+
+	before := testutil.ToFloat64(auditWriteFailures)
+	s.audit("token:grader", models.AuditActionReport, "ivanov", "", "pipeline=1")
+	if failures := testutil.ToFloat64(auditWriteFailures) - before; failures != 1 {
+		t.Fatalf("Expected one audit write failure, counted %g", failures)
+	}
+}
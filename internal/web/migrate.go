@@ -0,0 +1,55 @@
+package web
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	zlog "github.com/bigredeye/notmanytask/pkg/log"
+)
+
+// Migrate implements the migrate subcommand: migrate [-config path] [-to version] [-dry-run].
+// Without -to the schema is migrated to the latest version.
+func Migrate(args []string) error {
+	target := flag.Int("to", database.LatestSchemaVersion, "Target schema version, older versions are rolled back")
+	dryRun := flag.Bool("dry-run", false, "Print SQL statements without executing them")
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return err
+	}
+
+	config, err := config.ParseConfig()
+	if err != nil {
+		return err
+	}
+	if driver := config.DataBase.Driver; driver != "" && driver != "postgres" {
+		return fmt.Errorf("database driver %q has no schema to migrate", driver)
+	}
+
+	logger, err := zlog.Init(config.Log)
+	if err != nil {
+		return errors.Wrap(err, "Failed to init logger")
+	}
+	defer func() {
+		_ = zlog.Sync()
+	}()
+
+	db, err := database.ConnectDataBase(logger.Named("database"), postgresDSN(config))
+	if err != nil {
+		return errors.Wrap(err, "Failed to open database")
+	}
+
+	current, err := db.SchemaVersion()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get schema version")
+	}
+	fmt.Fprintf(os.Stderr, "Migrating schema from version %d to %d\n", current, *target)
+
+	return db.MigrateTo(*target, database.MigrateOptions{
+		DryRun: *dryRun,
+		Output: os.Stdout,
+	})
+}
@@ -0,0 +1,172 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/gitlab"
+	lf "github.com/bigredeye/notmanytask/internal/logfield"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+// Operations below are shared by the API and the admin panel.
+// They return the HTTP status code describing the error together with the error itself.
+
+func (s *server) findUserForOperation(login string) (*models.User, int, error) {
+	user, err := s.db.FindUserByGitlabLogin(login)
+	if err != nil {
+		s.logger.Error("Failed to get user by login", lf.GitlabLogin(login))
+		return nil, http.StatusNotFound, fmt.Errorf("not found user")
+	}
+	return user, http.StatusOK, nil
+}
+
+// findProjectOwner returns nil if nobody owns the project, project names are not stored and are matched group by group.
+func findProjectOwner(groups config.GroupsConfig, db database.Store, gitlab *gitlab.Client, project string) (*models.User, error) {
+	for _, group := range groups {
+		if !strings.HasPrefix(project, group.Name+"-") {
+			continue
+		}
+		users, err := db.ListGroupUsers(group.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if user.GitlabLogin != nil && gitlab.MakeProjectName(user) == project {
+				return user, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *server) overrideScore(actor, login, task string, score int, status models.PipelineStatus) (int, error) {
+	if _, code, err := s.findUserForOperation(login); err != nil {
+		return code, err
+	}
+
+	if !s.deadlines.AnyGroupHasTask(task) {
+		return http.StatusBadRequest, fmt.Errorf("unknown task %s", task)
+	}
+
+	overrides, err := s.db.ListUserOverrides(login)
+	if err != nil {
+		s.logger.Error("Failed to list user overrides", lf.GitlabLogin(login), zap.Error(err))
+		return http.StatusInternalServerError, err
+	}
+	oldValue := ""
+	for _, override := range overrides {
+		if override.Task == task {
+			oldValue = formatOverride(override.Task, override.Score, override.Status)
+		}
+	}
+
+	err = s.db.AddOverride(login, task, score, status,
+		auditRecord(actor, models.AuditActionOverride, login, oldValue, formatOverride(task, score, status)))
+	if err != nil {
+		s.logger.Error("Failed to override score", zap.String("task", task), lf.GitlabLogin(login), zap.Int("score", score), zap.String("status", status), zap.Error(err))
+		return http.StatusInternalServerError, err
+	}
+	s.logger.Info("Score was overriden", zap.String("task", task), lf.GitlabLogin(login), zap.Int("score", score), zap.String("status", status))
+	s.events.NotifyUser(login)
+	return http.StatusOK, nil
+}
+
+func (s *server) changeGroup(actor, login, groupName string) (int, error) {
+	user, code, err := s.findUserForOperation(login)
+	if err != nil {
+		return code, err
+	}
+
+	if s.config.Groups.FindGroup(groupName) == nil {
+		s.logger.Error("Failed to find group with name", zap.String("group_name", groupName))
+		return http.StatusNotFound, fmt.Errorf("not found group")
+	}
+	oldGroup := user.GroupName
+	user.GroupName = groupName
+
+	err = s.db.SetUserGroupName(user, auditRecord(actor, models.AuditActionChangeGroup, login, oldGroup, groupName))
+	if err != nil {
+		s.logger.Error("Failed to change group", lf.GitlabLogin(login), zap.String("group_name", groupName), zap.Error(err))
+		return http.StatusInternalServerError, err
+	}
+	s.logger.Info("Group was changed", lf.GitlabLogin(login), zap.String("group_name", groupName))
+	s.events.NotifyUser(login)
+	return http.StatusOK, nil
+}
+
+func (s *server) setRetakePolicy(actor, login, policy string) (int, error) {
+	user, code, err := s.findUserForOperation(login)
+	if err != nil {
+		return code, err
+	}
+
+	if policy != "" {
+		groupDeadlines := s.deadlines.GroupDeadlines(user.GroupName)
+		if groupDeadlines == nil || groupDeadlines.GetRetakePolicy(policy) == nil {
+			return http.StatusBadRequest, fmt.Errorf("unknown retake policy %s", policy)
+		}
+	}
+	oldPolicy := user.RetakePolicy
+	user.RetakePolicy = policy
+
+	err = s.db.SetUserRetakePolicy(user)
+	if err != nil {
+		s.logger.Error("Failed to set retake policy", lf.GitlabLogin(login), zap.String("policy", policy), zap.Error(err))
+		return http.StatusInternalServerError, err
+	}
+	s.logger.Info("Retake policy was set", lf.GitlabLogin(login), zap.String("policy", policy))
+	s.audit(actor, models.AuditActionRetake, login, oldPolicy, policy)
+	s.events.NotifyUser(login)
+	return http.StatusOK, nil
+}
+
+func (s *server) setRole(actor, login, role string) (int, error) {
+	parsed, err := models.ParseRole(role)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	user, code, err := s.findUserForOperation(login)
+	if err != nil {
+		return code, err
+	}
+	oldRole := user.Role
+	user.Role = parsed
+
+	err = s.db.SetUserRole(user)
+	if err != nil {
+		s.logger.Error("Failed to set role", lf.GitlabLogin(login), zap.String("role", role), zap.Error(err))
+		return http.StatusInternalServerError, err
+	}
+	s.logger.Info("Role was set", lf.GitlabLogin(login), zap.String("role", role))
+	s.audit(actor, models.AuditActionRole, login, string(oldRole), string(parsed))
+	return http.StatusOK, nil
+}
+
+// userRole combines the role stored in the database with the roles from config.
+func (s *server) userRole(user *models.User) models.Role {
+	role := user.Role
+	if user.GitlabLogin == nil {
+		return role
+	}
+	for _, granted := range []struct {
+		role   models.Role
+		logins []string
+	}{
+		{models.RoleAdmin, s.config.Roles.Admins},
+		{models.RoleTA, s.config.Roles.TAs},
+	} {
+		for _, login := range granted.logins {
+			if login == *user.GitlabLogin && !role.AtLeast(granted.role) {
+				role = granted.role
+			}
+		}
+	}
+	return role
+}
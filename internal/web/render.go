@@ -69,6 +69,8 @@ func (s *server) handleFlagSubmit(c *gin.Context) {
 		return
 	}
 
+	s.audit(userActor(user), models.AuditActionSubmitFlag, *user.GitlabLogin, "", flag)
+	s.events.NotifyUser(*user.GitlabLogin)
 	s.RenderSubmitFlagPageDetails(c, "", "The matrix has you...")
 }
 
@@ -88,10 +90,12 @@ type Links struct {
 	Submits         string
 	Logout          string
 	SubmitFlag      string
+	// Admin is set only for TAs and admins
+	Admin string
 }
 
 func (s *server) makeLinks(user *models.User) *Links {
-	return &Links{
+	links := &Links{
 		Deadlines:       s.config.Endpoints.Home,
 		Standings:       s.config.Endpoints.Standings,
 		TasksRepository: s.config.GitLab.TaskUrlPrefix,
@@ -100,11 +104,15 @@ func (s *server) makeLinks(user *models.User) *Links {
 		Logout:          s.config.Endpoints.Logout,
 		SubmitFlag:      s.config.Endpoints.Flag,
 	}
+	if s.userRole(user).AtLeast(models.RoleTA) {
+		links.Admin = s.config.Endpoints.Admin
+	}
+	return links
 }
 
 func (s *server) RenderHomePage(c *gin.Context) {
 	user := c.MustGet("user").(*models.User)
-	scores, err := s.scorer.CalcUserScores(user)
+	scores, err := s.materializer.UserScores(user)
 	reverseScores(scores)
 
 	c.HTML(http.StatusOK, "home.tmpl", gin.H{
@@ -118,24 +126,6 @@ func (s *server) RenderHomePage(c *gin.Context) {
 	})
 }
 
-func (s *server) RenderCheaterPage(c *gin.Context) {
-	user, err := s.db.FindUserByGitlabLogin(c.Query("login"))
-	var scores *scorer.UserScores
-	if err == nil {
-		scores, err = s.scorer.CalcUserScores(user)
-	}
-	reverseScores(scores)
-
-	c.HTML(http.StatusOK, "home.tmpl", gin.H{
-		"CourseName": s.config.Server.CourseName,
-		"Title":      s.config.Server.CourseName,
-		"Config":     s.config,
-		"Scores":     scores,
-		"Error":      err,
-		"Links":      s.makeLinks(user),
-	})
-}
-
 func reverseScoreboardGroups(standings *scorer.Standings) {
 	generic.ReverseSlice(standings.Deadlines.Assignments)
 	for i := range standings.Users {
@@ -156,8 +146,11 @@ func (s *server) doRenderStandingsPage(c *gin.Context, name string, filter score
 		links = s.makeLinks(user)
 	}
 
-	scores, err := s.cache.Fetch(fmt.Sprintf("scores/%s/%s", group, name), time.Second*10, func() (interface{}, error) {
-		scores, err := s.scorer.CalcScoreboardWithFilter(group, filter)
+	key := fmt.Sprintf("scores/%s/%s", group, name)
+	cached := s.cache.Get(key)
+	observeStandingsCache(cached != nil && !cached.Expired())
+	scores, err := s.cache.Fetch(key, time.Second*10, func() (interface{}, error) {
+		scores, err := s.materializer.Standings(group, filter)
 		reverseScoreboardGroups(scores)
 		return scores, err
 	})
@@ -167,6 +160,7 @@ func (s *server) doRenderStandingsPage(c *gin.Context, name string, filter score
 		"Config":      s.config,
 		"GroupConfig": s.config.Groups.FindGroup(group),
 		"Standings":   scores.Value().(*scorer.Standings),
+		"Retakes":     name == "retakes",
 		"Error":       err,
 		"Links":       links,
 	})
@@ -178,22 +172,6 @@ func (s *server) RenderStandingsPage(c *gin.Context) {
 
 func (s *server) RenderRetakesPage(c *gin.Context) {
 	s.doRenderStandingsPage(c, "retakes", func(user *models.User) bool {
-		return user.HasRetake
-	})
-}
-
-func (s *server) RenderStandingsCheaterPage(c *gin.Context) {
-	group := "hse"
-	user, _ := s.db.FindUserByGitlabLogin(c.Query("login"))
-	scores, err := s.scorer.CalcScoreboard(group)
-	reverseScoreboardGroups(scores)
-	c.HTML(http.StatusOK, "standings.tmpl", gin.H{
-		"CourseName":  s.config.Server.CourseName,
-		"Title":       s.config.Server.CourseName,
-		"Config":      s.config,
-		"GroupConfig": s.config.Groups.FindGroup(group),
-		"Standings":   scores,
-		"Error":       err,
-		"Links":       s.makeLinks(user),
+		return user.RetakePolicy != ""
 	})
 }
@@ -0,0 +1,70 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/scorer"
+)
+
+func TestRenderStandingsGrade(t *testing.T) {
+	tmpl, err := buildHTMLTemplates(templateFuncs())
+	if err != nil {
+		t.Fatal("Failed to build templates:", err)
+	}
+
+	grade := 8.5
+	standings := &scorer.Standings{
+		Deadlines: &deadlines.Deadlines{Scoring: deadlines.Scoring{Grade: &deadlines.Grading{}}},
+		Users: []*scorer.UserScores{
+			{User: scorer.User{FirstName: "Ivan", LastName: "Ivanov", GitlabLogin: "ivanov"}, FinalGrade: &grade},
+			{User: scorer.User{FirstName: "Petr", LastName: "Petrov", GitlabLogin: "petrov"}},
+		},
+	}
+
+	out := strings.Builder{}
+	err = tmpl.ExecuteTemplate(&out, "standings.tmpl", map[string]interface{}{
+		"Config":      &config.Config{},
+		"GroupConfig": &config.GroupConfig{Name: "hse", ShowMarks: true},
+		"Standings":   standings,
+	})
+	if err != nil {
+		t.Fatal("Failed to render standings:", err)
+	}
+
+	if !strings.Contains(out.String(), "<td>8.5</td>") {
+		t.Errorf("Grade 8.5 is not rendered")
+	}
+	if strings.Contains(out.String(), "%!") {
+		t.Errorf("Standings contain formatting errors")
+	}
+}
+
+func TestRenderAdminHomePreview(t *testing.T) {
+	tmpl, err := buildHTMLTemplates(templateFuncs())
+	if err != nil {
+		t.Fatal("Failed to build templates:", err)
+	}
+
+	conf := &config.Config{}
+	conf.Endpoints.Events = "/events"
+	for _, preview := range []bool{false, true} {
+		out := strings.Builder{}
+		err = tmpl.ExecuteTemplate(&out, "home.tmpl", map[string]interface{}{
+			"Config":  conf,
+			"Scores":  &scorer.UserScores{},
+			"Links":   &Links{},
+			"Preview": preview,
+		})
+		if err != nil {
+			t.Fatal("Failed to render home page:", err)
+		}
+
+		// The events stream of the student preview would deliver the events of the admin
+		if subscribed := strings.Contains(out.String(), "EventSource"); subscribed == preview {
+			t.Errorf("Home page subscribes to events: %v, preview: %v", subscribed, preview)
+		}
+	}
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/bigredeye/notmanytask/internal/tgbot"
 	zlog "github.com/bigredeye/notmanytask/pkg/log"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
 )
 
 func Run() error {
@@ -38,13 +40,7 @@ func Run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	db, err := database.OpenDataBase(logger.Named("database"), fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
-		config.DataBase.User,
-		config.DataBase.Pass,
-		config.DataBase.Host,
-		config.DataBase.Port,
-		config.DataBase.Name,
-	))
+	db, err := openStore(config, logger)
 	if err != nil {
 		return errors.Wrap(err, "Failed to open database")
 	}
@@ -75,8 +71,14 @@ func Run() error {
 	}
 
 	scorer := scorer.NewScorer(db, deadlines, git)
+	materializer := scorer.NewMaterializer(config, logger.Named("scorer.materializer"))
+
+	events := newEventHub(config, logger.Named("events"), db, git, materializer)
+	pipelines.OnStatusChange(events.NotifyProject)
 
-	wg.Add(5)
+	prometheus.MustRegister(deadlines)
+
+	wg.Add(7)
 	go func() {
 		defer wg.Done()
 		deadlines.Run(ctx)
@@ -97,8 +99,38 @@ func Run() error {
 		defer wg.Done()
 		bot.Run(ctx)
 	}()
+	go func() {
+		defer wg.Done()
+		materializer.Run(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		events.Run(ctx)
+	}()
 
-	s := newServer(config, logger.Named("server"), db, deadlines, projects, pipelines, scorer, git)
+	s := newServer(config, logger.Named("server"), db, deadlines, projects, pipelines, scorer, materializer, events, git)
 
 	return errors.Wrap(s.run(), "Server failed")
 }
+
+func postgresDSN(config *config.Config) string {
+	return fmt.Sprintf("postgresql://%s:%s@%s:%d/%s",
+		config.DataBase.User,
+		config.DataBase.Pass,
+		config.DataBase.Host,
+		config.DataBase.Port,
+		config.DataBase.Name,
+	)
+}
+
+func openStore(config *config.Config, logger *zap.Logger) (database.Store, error) {
+	switch config.DataBase.Driver {
+	case "", "postgres":
+		return database.OpenDataBase(logger.Named("database"), postgresDSN(config))
+	case "memory":
+		logger.Warn("Using in-memory database, all data will be lost on restart")
+		return database.NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", config.DataBase.Driver)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/karlseguin/ccache/v2"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
 	"github.com/bigredeye/notmanytask/internal/config"
@@ -26,12 +27,15 @@ type server struct {
 	logger *zap.Logger
 
 	auth      *AuthClient
-	db        *database.DataBase
+	db        database.Store
 	deadlines *deadlines.Fetcher
 	projects  *gitlab.ProjectsMaker
 	pipelines *gitlab.PipelinesFetcher
 	scorer    *scorer.Scorer
 	gitlab    *gitlab.Client
+	// materializer serves precalculated scores to the pages
+	materializer *scorer.Materializer
+	events       *eventHub
 
 	cache *ccache.Cache
 }
@@ -39,11 +43,13 @@ type server struct {
 func newServer(
 	config *config.Config,
 	logger *zap.Logger,
-	db *database.DataBase,
+	db database.Store,
 	deadlines *deadlines.Fetcher,
 	projects *gitlab.ProjectsMaker,
 	pipelines *gitlab.PipelinesFetcher,
 	scorer *scorer.Scorer,
+	materializer *scorer.Materializer,
+	events *eventHub,
 	gitlab *gitlab.Client,
 ) *server {
 	return &server{
@@ -57,6 +63,22 @@ func newServer(
 		scorer:    scorer,
 		gitlab:    gitlab,
 		cache:     ccache.New(ccache.Configure()),
+
+		materializer: materializer,
+		events:       events,
+	}
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"inc": func(i int) int {
+			return i + 1
+		},
+		"prettifyTaskName": filepath.Base,
+		// deref prints optional values, printf does not follow pointers
+		"deref": func(value *float64) float64 {
+			return *value
+		},
 	}
 }
 
@@ -66,13 +88,7 @@ func buildHTMLTemplates(funcMap template.FuncMap) (*template.Template, error) {
 }
 
 func (s *server) run() error {
-	funcs := template.FuncMap{
-		"inc": func(i int) int {
-			return i + 1
-		},
-		"prettifyTaskName": filepath.Base,
-	}
-	tmpl, err := buildHTMLTemplates(funcs)
+	tmpl, err := buildHTMLTemplates(templateFuncs())
 	if err != nil {
 		return errors.Wrap(err, "Failed to build html templates")
 	}
@@ -82,6 +98,7 @@ func (s *server) run() error {
 
 	r.Use(ginzap.Ginzap(s.logger, time.RFC3339, true))
 	r.Use(ginzap.RecoveryWithZap(s.logger, true))
+	r.Use(observeRequests(s.config.Endpoints.Events))
 
 	s.logger.Info("Defined templates are: ", zap.String("tmpl", tmpl.DefinedTemplates()))
 	r.SetHTMLTemplate(tmpl)
@@ -99,6 +116,7 @@ func (s *server) run() error {
 	if err != nil {
 		return errors.Wrap(err, "Failed to setup api service")
 	}
+	setupAdminPanel(s, r)
 
 	r.GET("/ping", func(c *gin.Context) {
 		c.String(http.StatusOK, "pong "+fmt.Sprint(time.Now().Unix()))
@@ -109,10 +127,20 @@ func (s *server) run() error {
 	r.GET(s.config.Endpoints.Retakes, s.validateSession(true), s.RenderRetakesPage)
 	r.POST(s.config.Endpoints.Flag, s.validateSession(true), s.handleFlagSubmit)
 	r.GET(s.config.Endpoints.Standings /* no need to validate session */, s.RenderStandingsPage)
+	r.GET(s.config.Endpoints.Events, s.handleEvents)
 	r.GET("/private/solutions/:group/:task", s.handleChuckNorris)
 
 	r.StaticFS("/static", http.FS(web.StaticContent))
 
+	if s.config.Server.MetricsAddress != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", promhttp.Handler())
+			s.logger.Info("Starting metrics server", zap.String("bind_address", s.config.Server.MetricsAddress))
+			s.logger.Error("Metrics server failed", zap.Error(http.ListenAndServe(s.config.Server.MetricsAddress, mux)))
+		}()
+	}
+
 	s.logger.Info("Starting server", zap.String("bind_address", s.config.Server.ListenAddress))
 	return r.Run(s.config.Server.ListenAddress)
 }
@@ -0,0 +1,321 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/api"
+	"github.com/bigredeye/notmanytask/internal/deadlines"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+const (
+	contextKeyAPIToken = "apiToken"
+	apiTokenPrefix     = "nmt_"
+	// Last usage is not written on every request, graders report too often
+	apiTokenTouchInterval = time.Minute
+)
+
+// headerToken reads the token either from the Authorization header or from the token header.
+func headerToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return c.GetHeader("token")
+}
+
+// bodyToken reads the token field of the request body, the body is buffered to be bound by the handler.
+// Used only if ServerConfig.AllowBodyTokens is set.
+func bodyToken(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	if c.ContentType() != binding.MIMEJSON {
+		return c.PostForm("token")
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	req := struct {
+		Token string `json:"token"`
+	}{}
+	_ = json.Unmarshal(body, &req)
+	return req.Token
+}
+
+// requireScope replaces the shared token list, the authorized token is available via apiToken.
+func (s apiService) requireScope(scope models.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		abort := func(code int, err string) {
+			c.JSON(code, &api.Status{
+				Ok:    false,
+				Error: err,
+			})
+			c.Abort()
+		}
+
+		plain := headerToken(c)
+		if plain == "" && s.config.Server.AllowBodyTokens {
+			if plain = bodyToken(c); plain != "" {
+				s.log.Warn("Token in request body is deprecated, use Authorization header", zap.String("path", c.FullPath()))
+			}
+		}
+		if plain == "" {
+			abort(http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		token, err := s.server.db.FindAPIToken(models.HashAPIToken(plain))
+		now := time.Now()
+		if err != nil || !token.Active(now) {
+			s.log.Warn("Unknown token", zap.String("path", c.FullPath()))
+			abort(http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		if !token.HasScope(scope) {
+			s.log.Warn("Token scope is not granted", zap.String("token", token.Name), zap.String("scope", string(scope)))
+			abort(http.StatusForbidden, fmt.Sprintf("Token has no %s scope", scope))
+			return
+		}
+
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > apiTokenTouchInterval {
+			if err := s.server.db.TouchAPIToken(token.ID, now); err != nil {
+				s.log.Error("Failed to update token usage", zap.String("token", token.Name), zap.Error(err))
+			}
+		}
+
+		c.Set(contextKeyAPIToken, token)
+		c.Next()
+	}
+}
+
+// apiToken must be called after requireScope.
+func apiToken(c *gin.Context) *models.APIToken {
+	return c.MustGet(contextKeyAPIToken).(*models.APIToken)
+}
+
+func (s apiService) checkGroup(c *gin.Context, group string) (int, error) {
+	if token := apiToken(c); !token.AllowsGroup(group) {
+		return http.StatusForbidden, fmt.Errorf("token is restricted to group %s", token.GroupName)
+	}
+	return http.StatusOK, nil
+}
+
+// checkUser allows the operation on the student if the token is not restricted to another group.
+func (s apiService) checkUser(c *gin.Context, login string) (int, error) {
+	if apiToken(c).GroupName == "" {
+		return http.StatusOK, nil
+	}
+	user, code, err := s.server.findUserForOperation(login)
+	if err != nil {
+		return code, err
+	}
+	return s.checkGroup(c, user.GroupName)
+}
+
+// checkUnrestricted guards the endpoints that are not scoped by group.
+func (s apiService) checkUnrestricted(c *gin.Context) (int, error) {
+	if token := apiToken(c); token.GroupName != "" {
+		return http.StatusForbidden, fmt.Errorf("token is restricted to group %s", token.GroupName)
+	}
+	return http.StatusOK, nil
+}
+
+// createAPIToken returns the plain token, it is not stored anywhere and is shown only once.
+func (s *server) createAPIToken(actor, name string, scopes []string, group string, expiresAt *time.Time) (string, int, error) {
+	if name == "" {
+		return "", http.StatusBadRequest, fmt.Errorf("token name is required")
+	}
+	if len(scopes) == 0 {
+		return "", http.StatusBadRequest, fmt.Errorf("at least one scope is required")
+	}
+	if group != "" && s.config.Groups.FindGroup(group) == nil {
+		return "", http.StatusNotFound, fmt.Errorf("not found group")
+	}
+
+	token := &models.APIToken{
+		Name:      name,
+		GroupName: group,
+		ExpiresAt: expiresAt,
+	}
+	parsed := make([]models.Scope, 0, len(scopes))
+	for _, scope := range scopes {
+		scope, err := models.ParseScope(scope)
+		if err != nil {
+			return "", http.StatusBadRequest, err
+		}
+		parsed = append(parsed, scope)
+	}
+	token.SetScopes(parsed)
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", http.StatusInternalServerError, err
+	}
+	plain := apiTokenPrefix + hex.EncodeToString(random)
+	token.Hash = models.HashAPIToken(plain)
+
+	if err := s.db.CreateAPIToken(token); err != nil {
+		s.logger.Error("Failed to create token", zap.String("token", name), zap.Error(err))
+		return "", http.StatusInternalServerError, err
+	}
+	s.logger.Info("Token was created", zap.String("token", name), zap.String("scopes", token.Scopes), zap.String("group_name", group))
+	s.audit(actor, models.AuditActionCreateToken, "", "", formatAPIToken(token))
+	return plain, http.StatusOK, nil
+}
+
+func (s *server) revokeAPIToken(actor, name string) (int, error) {
+	if err := s.db.RevokeAPIToken(name, time.Now()); err != nil {
+		s.logger.Error("Failed to revoke token", zap.String("token", name), zap.Error(err))
+		return http.StatusNotFound, err
+	}
+	s.logger.Info("Token was revoked", zap.String("token", name))
+	s.audit(actor, models.AuditActionRevokeToken, "", "name="+name, "")
+	return http.StatusOK, nil
+}
+
+func formatAPIToken(token *models.APIToken) string {
+	res := fmt.Sprintf("name=%s scopes=%s", token.Name, token.Scopes)
+	if token.GroupName != "" {
+		res += " group=" + token.GroupName
+	}
+	if token.ExpiresAt != nil {
+		res += " expires=" + token.ExpiresAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+func parseTokenExpiration(expiresAt string) (*time.Time, error) {
+	if expiresAt == "" {
+		return nil, nil
+	}
+	date := deadlines.Date{}
+	if err := date.UnmarshalText([]byte(expiresAt)); err != nil {
+		return nil, fmt.Errorf("failed to parse expiration date: %w", err)
+	}
+	return &date.Time, nil
+}
+
+func (s apiService) createToken(c *gin.Context) {
+	s.log.Info("Handling token creation request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to create token", zap.Error(err))
+		c.JSON(code, &api.TokenResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	req := api.TokenRequest{}
+	if err := c.Bind(&req); err != nil {
+		onError(http.StatusBadRequest, fmt.Errorf("failed to parse request body: %w", err))
+		return
+	}
+	if code, err := s.checkUnrestricted(c); err != nil {
+		onError(code, err)
+		return
+	}
+	// Tokens cannot be used to escalate their own privileges
+	creator := apiToken(c)
+	for _, scope := range req.Scopes {
+		if !creator.HasScope(models.Scope(scope)) {
+			onError(http.StatusForbidden, fmt.Errorf("token has no %s scope to grant", scope))
+			return
+		}
+	}
+	expiresAt, err := parseTokenExpiration(req.ExpiresAt)
+	if err != nil {
+		onError(http.StatusBadRequest, err)
+		return
+	}
+
+	plain, code, err := s.server.createAPIToken(tokenActor(creator), req.Name, req.Scopes, req.Group, expiresAt)
+	if err != nil {
+		onError(code, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.TokenResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+		Token: plain,
+	})
+}
+
+func (s apiService) listTokens(c *gin.Context) {
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to list tokens", zap.Error(err))
+		c.JSON(code, &api.ListTokensResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	if code, err := s.checkUnrestricted(c); err != nil {
+		onError(code, err)
+		return
+	}
+	tokens, err := s.server.db.ListAPITokens()
+	if err != nil {
+		onError(http.StatusInternalServerError, fmt.Errorf("failed to list tokens: %w", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.ListTokensResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+		Tokens: tokens,
+	})
+}
+
+func (s apiService) revokeToken(c *gin.Context) {
+	s.log.Info("Handling token revocation request")
+	onError := func(code int, err error) {
+		s.log.Warn("Failed to revoke token", zap.Error(err))
+		c.JSON(code, &api.TokenResponse{
+			Status: api.Status{
+				Ok:    false,
+				Error: err.Error(),
+			}},
+		)
+	}
+
+	if code, err := s.checkUnrestricted(c); err != nil {
+		onError(code, err)
+		return
+	}
+	name := c.Query("name")
+	if name == "" {
+		onError(http.StatusBadRequest, fmt.Errorf("token name is required"))
+		return
+	}
+	if code, err := s.server.revokeAPIToken(tokenActor(apiToken(c)), name); err != nil {
+		onError(code, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, &api.TokenResponse{
+		Status: api.Status{
+			Ok: true,
+		},
+	})
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/bigredeye/notmanytask/internal/config"
+	"github.com/bigredeye/notmanytask/internal/database"
+	"github.com/bigredeye/notmanytask/internal/models"
+)
+
+func newTestAPIService(conf *config.Config) apiService {
+	s := &server{config: conf, logger: zap.NewNop(), db: database.NewMemoryStore()}
+	return apiService{webService{s, conf, s.logger}}
+}
+
+func createTestToken(t *testing.T, s apiService, name string, expiresAt *time.Time, scopes ...string) string {
+	t.Helper()
+	return createGroupTestToken(t, s, name, "", expiresAt, scopes...)
+}
+
+func createGroupTestToken(t *testing.T, s apiService, name, group string, expiresAt *time.Time, scopes ...string) string {
+	t.Helper()
+	plain, _, err := s.server.createAPIToken("test", name, scopes, group, expiresAt)
+	if err != nil {
+		t.Fatal("Failed to create token:", err)
+	}
+	return plain
+}
+
+func TestRequireScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.Config{}
+	s := newTestAPIService(conf)
+
+	report := createTestToken(t, s, "grader", nil, string(models.ScopeReport))
+	standings := createTestToken(t, s, "script", nil, string(models.ScopeReadStandings))
+	past := time.Now().Add(-time.Hour)
+	expired := createTestToken(t, s, "expired", &past, string(models.ScopeReport))
+	revoked := createTestToken(t, s, "revoked", nil, string(models.ScopeReport))
+	if _, err := s.server.revokeAPIToken("test", "revoked"); err != nil {
+		t.Fatal("Failed to revoke token:", err)
+	}
+
+	r := gin.New()
+	r.POST("/report", s.requireScope(models.ScopeReport), func(c *gin.Context) {
+		c.String(http.StatusOK, apiToken(c).Name)
+	})
+
+	for _, test := range []struct {
+		name    string
+		prepare func(req *http.Request)
+		body    string
+		code    int
+	}{
+		{name: "bearer", prepare: func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+report) }, code: http.StatusOK},
+		{name: "token header", prepare: func(req *http.Request) { req.Header.Set("token", report) }, code: http.StatusOK},
+		{name: "missing", prepare: func(req *http.Request) {}, code: http.StatusUnauthorized},
+		{name: "unknown", prepare: func(req *http.Request) { req.Header.Set("token", "nmt_unknown") }, code: http.StatusUnauthorized},
+		{name: "expired", prepare: func(req *http.Request) { req.Header.Set("token", expired) }, code: http.StatusUnauthorized},
+		{name: "revoked", prepare: func(req *http.Request) { req.Header.Set("token", revoked) }, code: http.StatusUnauthorized},
+		{name: "scope", prepare: func(req *http.Request) { req.Header.Set("token", standings) }, code: http.StatusForbidden},
+		{name: "body", prepare: func(req *http.Request) { req.Header.Set("Content-Type", "application/json") }, body: `{"token": "` + report + `"}`, code: http.StatusUnauthorized},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(test.body))
+			test.prepare(req)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != test.code {
+				t.Fatalf("Unexpected code %d, expected %d: %s", w.Code, test.code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequireScopeBodyTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.Config{}
+	conf.Server.AllowBodyTokens = true
+	s := newTestAPIService(conf)
+	report := createTestToken(t, s, "grader", nil, string(models.ScopeReport))
+
+	r := gin.New()
+	r.POST("/report", s.requireScope(models.ScopeReport), func(c *gin.Context) {
+		req := struct {
+			Task string `json:"task" form:"task"`
+		}{}
+		if err := c.Bind(&req); err != nil {
+			return
+		}
+		c.String(http.StatusOK, req.Task)
+	})
+
+	for _, test := range []struct {
+		contentType string
+		body        string
+	}{
+		{contentType: "application/json", body: `{"token": "` + report + `", "task": "sum"}`},
+		{contentType: "application/x-www-form-urlencoded", body: "token=" + report + "&task=sum"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/report", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		// The handler still binds the body read by the middleware
+		if w.Code != http.StatusOK || w.Body.String() != "sum" {
+			t.Fatalf("Unexpected response to %s: %d %s", test.contentType, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGroupTokenCannotManageGlobalState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	conf := &config.Config{Groups: config.GroupsConfig{{Name: "hse"}}}
+	s := newTestAPIService(conf)
+	restricted := createGroupTestToken(t, s, "hse-ta", "hse", nil, string(models.ScopeManageUsers))
+
+	r := gin.New()
+	r.POST("/role", s.requireScope(models.ScopeManageUsers), s.setRole)
+	r.GET("/audit", s.requireScope(models.ScopeManageUsers), s.listAudit)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/role", strings.NewReader(`{"login": "ivanov", "role": "admin"}`)),
+		httptest.NewRequest(http.MethodGet, "/audit", nil),
+	} {
+		req.Header.Set("Authorization", "Bearer "+restricted)
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("Group token got %d on %s: %s", w.Code, req.URL.Path, w.Body.String())
+		}
+	}
+}
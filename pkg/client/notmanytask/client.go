@@ -1,6 +1,7 @@
 package notmanytask
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -12,7 +13,6 @@ import (
 
 type Client struct {
 	client *resty.Client
-	token  string
 }
 
 func NewClient(endpoint, token string) (*Client, error) {
@@ -21,17 +21,25 @@ func NewClient(endpoint, token string) (*Client, error) {
 		SetTimeout(time.Second * 10).
 		SetRetryCount(3)
 
-	client.Header.Add("Token", token)
+	client.SetAuthToken(token)
 
-	return &Client{client, token}, nil
+	return &Client{client}, nil
 }
 
 func (c *Client) LoadStandings(group string) (*scorer.Standings, error) {
+	return c.LoadStandingsAt(group, "")
+}
+
+// LoadStandingsAt loads standings as they were at the given moment, empty at means now.
+func (c *Client) LoadStandingsAt(group, at string) (*scorer.Standings, error) {
 	res := &api.StandingsResponse{}
-	_, err := c.client.R().
+	req := c.client.R().
 		SetResult(res).
-		SetQueryParam("group", group).
-		Get("/api/standings")
+		SetQueryParam("group", group)
+	if at != "" {
+		req.SetQueryParam("at", at)
+	}
+	_, err := req.Get("/api/standings")
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch standings: %w", err)
@@ -44,6 +52,37 @@ func (c *Client) LoadStandings(group string) (*scorer.Standings, error) {
 	return res.Standings, nil
 }
 
+// ExportStandings returns the standings file in the given format, empty parameters select server defaults.
+func (c *Client) ExportStandings(group, at, format, columns, names string) ([]byte, error) {
+	req := c.client.R().SetQueryParam("group", group)
+	params := map[string]string{
+		"at":      at,
+		"format":  format,
+		"columns": columns,
+		"names":   names,
+	}
+	for key, value := range params {
+		if value != "" {
+			req = req.SetQueryParam(key, value)
+		}
+	}
+
+	res, err := req.Get("/api/standings/export")
+	if err != nil {
+		return nil, fmt.Errorf("failed to export standings: %w", err)
+	}
+
+	if res.IsError() {
+		status := &api.Status{}
+		if err = json.Unmarshal(res.Body(), status); err != nil {
+			return nil, fmt.Errorf("failed to export standings: %s", res.Status())
+		}
+		return nil, fmt.Errorf("failed to export standings: %s", status.Error)
+	}
+
+	return res.Body(), nil
+}
+
 func (c *Client) LoadUsers(group string) ([]*models.User, error) {
 	res := &api.GroupMembers{}
 	_, err := c.client.R().
@@ -66,7 +105,6 @@ func (c *Client) OverrideScore(user, task, status string, score int) error {
 	_, err := c.client.R().
 		SetResult(res).
 		SetBody(api.OverrideRequest{
-			Token:  c.token,
 			Task:   task,
 			Login:  user,
 			Score:  score,
@@ -84,6 +122,104 @@ func (c *Client) OverrideScore(user, task, status string, score int) error {
 	return nil
 }
 
+func (c *Client) SetRetakePolicy(user, policy string) error {
+	res := &api.RetakeResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetBody(api.RetakeRequest{
+			Login:  user,
+			Policy: policy,
+		}).
+		Post("/api/retake")
+	if err != nil {
+		return err
+	}
+
+	if !res.Ok {
+		return fmt.Errorf("failed to set retake policy: %s", res.Error)
+	}
+
+	return nil
+}
+
+func (c *Client) SetRole(user, role string) error {
+	res := &api.RoleResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetBody(api.RoleRequest{
+			Login: user,
+			Role:  role,
+		}).
+		Post("/api/role")
+	if err != nil {
+		return err
+	}
+
+	if !res.Ok {
+		return fmt.Errorf("failed to set role: %s", res.Error)
+	}
+
+	return nil
+}
+
+func (c *Client) ExtendDeadline(user, task, deadline, reason string) error {
+	res := &api.ExtensionResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetBody(api.ExtensionRequest{
+			Task:     task,
+			Login:    user,
+			Deadline: deadline,
+			Reason:   reason,
+		}).
+		Post("/api/extension")
+	if err != nil {
+		return err
+	}
+
+	if !res.Ok {
+		return fmt.Errorf("failed to extend deadline: %s", res.Error)
+	}
+
+	return nil
+}
+
+func (c *Client) RemoveExtension(user, task string) error {
+	res := &api.ExtensionResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetQueryParam("login", user).
+		SetQueryParam("task", task).
+		Delete("/api/extension")
+	if err != nil {
+		return err
+	}
+
+	if !res.Ok {
+		return fmt.Errorf("failed to remove extension: %s", res.Error)
+	}
+
+	return nil
+}
+
+func (c *Client) LoadExtensions(user string) ([]models.Extension, error) {
+	res := &api.ListExtensionsResponse{}
+	req := c.client.R().SetResult(res)
+	if user != "" {
+		req = req.SetQueryParam("login", user)
+	}
+	_, err := req.Get("/api/extension")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch extensions: %w", err)
+	}
+
+	if !res.Ok {
+		return nil, fmt.Errorf("failed to fetch extensions: %s", res.Error)
+	}
+
+	return res.Extensions, nil
+}
+
 func (c *Client) LoadSuccessfulSubmits(group, taskname string) ([]*scorer.User, error) {
 	standings, err := c.LoadStandings(group)
 	if err != nil {
@@ -109,3 +245,90 @@ func (c *Client) LoadSuccessfulSubmits(group, taskname string) ([]*scorer.User,
 
 	return res, nil
 }
+
+// LoadAuditRecords loads the latest audit records, empty filters match everything.
+func (c *Client) LoadAuditRecords(login, actor, action, since string, limit int) ([]models.AuditRecord, error) {
+	res := &api.AuditResponse{}
+	req := c.client.R().SetResult(res)
+	params := map[string]string{
+		"login":  login,
+		"actor":  actor,
+		"action": action,
+		"since":  since,
+	}
+	for key, value := range params {
+		if value != "" {
+			req = req.SetQueryParam(key, value)
+		}
+	}
+	if limit > 0 {
+		req = req.SetQueryParam("limit", fmt.Sprint(limit))
+	}
+
+	_, err := req.Get("/api/audit")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit records: %w", err)
+	}
+
+	if !res.Ok {
+		return nil, fmt.Errorf("failed to fetch audit records: %s", res.Error)
+	}
+
+	return res.Records, nil
+}
+
+// CreateToken returns the plain token, the server keeps only its hash.
+func (c *Client) CreateToken(name string, scopes []string, group, expiresAt string) (string, error) {
+	res := &api.TokenResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetBody(api.TokenRequest{
+			Name:      name,
+			Scopes:    scopes,
+			Group:     group,
+			ExpiresAt: expiresAt,
+		}).
+		Post("/api/tokens")
+	if err != nil {
+		return "", err
+	}
+
+	if !res.Ok {
+		return "", fmt.Errorf("failed to create token: %s", res.Error)
+	}
+
+	return res.Token, nil
+}
+
+func (c *Client) LoadTokens() ([]models.APIToken, error) {
+	res := &api.ListTokensResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		Get("/api/tokens")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tokens: %w", err)
+	}
+
+	if !res.Ok {
+		return nil, fmt.Errorf("failed to fetch tokens: %s", res.Error)
+	}
+
+	return res.Tokens, nil
+}
+
+func (c *Client) RevokeToken(name string) error {
+	res := &api.TokenResponse{}
+	_, err := c.client.R().
+		SetResult(res).
+		SetQueryParam("name", name).
+		Delete("/api/tokens")
+	if err != nil {
+		return err
+	}
+
+	if !res.Ok {
+		return fmt.Errorf("failed to revoke token: %s", res.Error)
+	}
+
+	return nil
+}